@@ -4,5 +4,5 @@ go 1.16
 
 require (
 	golang.org/x/sys v0.0.0-20210525143221-35b2ab0089ea // indirect
-	golang.org/x/term v0.0.0-20210503060354-a79de5458b56 // indirect
+	golang.org/x/term v0.0.0-20210503060354-a79de5458b56
 )
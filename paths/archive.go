@@ -0,0 +1,312 @@
+package paths
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// archiveEntry is a file, directory or symlink being transferred between a tree and an archive, or between two trees.
+type archiveEntry struct {
+	name     string // Slash-separated, and relative to the root of the archive
+	mode     os.FileMode
+	size     int64 // Regular files only
+	modified time.Time
+	target   string                        // Symlinks only
+	open     func() (io.ReadCloser, error) // Regular files only
+}
+
+// archiveSource calls fn for each entry in an archive, parents before children.
+type archiveSource func(fn func(entry *archiveEntry) error) error
+
+func (p *Path) archiveSource() archiveSource {
+	return func(fn func(entry *archiveEntry) error) error {
+		return p.Walk(func(path *Path, info os.FileInfo, err error) error {
+			if err != nil || path.path == p.path {
+				return err
+			}
+			entry := &archiveEntry{
+				name:     filepath.ToSlash(p.nameOf(path)),
+				mode:     info.Mode(),
+				size:     info.Size(),
+				modified: info.ModTime(),
+			}
+			switch {
+			case info.Mode()&os.ModeSymlink != 0:
+				if entry.target, err = path.tree.sys.Readlink(path.path); err != nil {
+					return err
+				}
+			case info.Mode().IsRegular():
+				entry.open = func() (io.ReadCloser, error) { return path.Open() }
+			case !info.IsDir():
+				// Devices, pipes, sockets etc. can't be archived.
+				return nil
+			}
+			return fn(entry)
+		})
+	}
+}
+
+func tarSource(reader io.Reader) archiveSource {
+	return func(fn func(entry *archiveEntry) error) error {
+		buffered := bufio.NewReader(reader)
+		if magic, err := buffered.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+			gz, err := gzip.NewReader(buffered)
+			if err != nil {
+				return err
+			}
+			defer gz.Close()
+			reader = gz
+		} else {
+			reader = buffered
+		}
+		tr := tar.NewReader(reader)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			entry := &archiveEntry{
+				mode:     os.FileMode(header.Mode).Perm(),
+				modified: header.ModTime,
+			}
+			if entry.name, err = cleanArchiveName(header.Name); err != nil {
+				return err
+			}
+			switch header.Typeflag {
+			case tar.TypeDir:
+				entry.mode |= os.ModeDir
+			case tar.TypeSymlink:
+				entry.mode |= os.ModeSymlink
+				entry.target = header.Linkname
+			case tar.TypeReg, tar.TypeRegA:
+				entry.open = func() (io.ReadCloser, error) { return io.NopCloser(tr), nil }
+			default:
+				continue
+			}
+			if entry.name == "" {
+				continue
+			}
+			if err = fn(entry); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func zipSource(reader io.ReaderAt, size int64) archiveSource {
+	return func(fn func(entry *archiveEntry) error) error {
+		zr, err := zip.NewReader(reader, size)
+		if err != nil {
+			return err
+		}
+		for _, file := range zr.File {
+			file := file
+			entry := &archiveEntry{
+				mode:     file.Mode(),
+				modified: file.Modified,
+			}
+			if entry.name, err = cleanArchiveName(file.Name); err != nil {
+				return err
+			}
+			switch {
+			case entry.mode&os.ModeSymlink != 0:
+				if entry.target, err = readZipFile(file); err != nil {
+					return err
+				}
+			case entry.mode.IsRegular():
+				entry.open = func() (io.ReadCloser, error) { return file.Open() }
+			case !entry.mode.IsDir():
+				continue
+			}
+			if entry.name == "" {
+				continue
+			}
+			if err = fn(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func readZipFile(file *zip.File) (string, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	b, err := io.ReadAll(reader)
+	return string(b), err
+}
+
+// cleanArchiveName normalises a slash-separated name from an archive, and rejects names that would escape the directory
+// the archive is being extracted to.
+func cleanArchiveName(name string) (string, error) {
+	name = path.Clean(strings.TrimLeft(name, "/"))
+	if strings.HasPrefix(name, "../") || name == ".." {
+		return "", ErrInvalid
+	}
+	if name == "." {
+		return "", nil
+	}
+	return name, nil
+}
+
+// extract writes every entry from source into p. Modes and modification times are applied to directories once all
+// entries have been written, so that they're not affected by the creation of their contents, and so that read-only
+// directories can still be populated.
+func (p *Path) extract(source archiveSource) error {
+	sys := p.tree.sys
+	var dirs []*archiveEntry
+	err := source(func(entry *archiveEntry) error {
+		dest := p.Join(filepath.FromSlash(entry.name)).path
+		switch {
+		case entry.mode.IsDir():
+			dirs = append(dirs, entry)
+			return sys.MkdirAll(dest, 0755)
+		case entry.mode&os.ModeSymlink != 0:
+			if err := sys.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+				return err
+			}
+			if _, err := sys.Lstat(dest); err == nil {
+				if err = sys.Remove(dest); err != nil {
+					return err
+				}
+			}
+			return sys.Symlink(entry.target, dest)
+		}
+		if err := sys.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		if err := p.extractFile(entry, dest); err != nil {
+			return err
+		}
+		if err := sys.Chmod(dest, entry.mode.Perm()); err != nil {
+			return err
+		}
+		return sys.Chtimes(dest, entry.modified, entry.modified)
+	})
+	if err != nil {
+		return err
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		dest := p.Join(filepath.FromSlash(dirs[i].name)).path
+		if err = sys.Chmod(dest, dirs[i].mode.Perm()); err != nil {
+			return err
+		}
+		if err = sys.Chtimes(dest, dirs[i].modified, dirs[i].modified); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Path) extractFile(entry *archiveEntry, dest string) error {
+	reader, err := entry.open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	writer, err := p.tree.sys.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, entry.mode.Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(writer, reader)
+	if err == nil {
+		err = writer.Close()
+	} else {
+		_ = writer.Close()
+	}
+	return err
+}
+
+func writeTar(writer io.Writer, source archiveSource) error {
+	tw := tar.NewWriter(writer)
+	err := source(func(entry *archiveEntry) error {
+		header := &tar.Header{
+			Name:    entry.name,
+			Mode:    int64(entry.mode.Perm()),
+			ModTime: entry.modified,
+		}
+		switch {
+		case entry.mode.IsDir():
+			header.Typeflag = tar.TypeDir
+			header.Name += "/"
+		case entry.mode&os.ModeSymlink != 0:
+			header.Typeflag = tar.TypeSymlink
+			header.Linkname = entry.target
+		default:
+			header.Typeflag = tar.TypeReg
+			header.Size = entry.size
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			return entry.copyTo(tw)
+		}
+		return tw.WriteHeader(header)
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+func writeZip(writer io.Writer, source archiveSource) error {
+	zw := zip.NewWriter(writer)
+	err := source(func(entry *archiveEntry) error {
+		header := &zip.FileHeader{
+			Name:     entry.name,
+			Modified: entry.modified,
+			Method:   zip.Deflate,
+		}
+		header.SetMode(entry.mode)
+		switch {
+		case entry.mode.IsDir():
+			header.Name += "/"
+			header.Method = zip.Store
+			_, err := zw.CreateHeader(header)
+			return err
+		case entry.mode&os.ModeSymlink != 0:
+			header.Method = zip.Store
+			w, err := zw.CreateHeader(header)
+			if err == nil {
+				_, err = io.WriteString(w, entry.target)
+			}
+			return err
+		}
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		return entry.copyTo(w)
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func (e *archiveEntry) copyTo(writer io.Writer) error {
+	reader, err := e.open()
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(writer, reader)
+	if err == nil {
+		err = reader.Close()
+	} else {
+		_ = reader.Close()
+	}
+	return err
+}
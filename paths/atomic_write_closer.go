@@ -0,0 +1,128 @@
+package paths
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"os"
+)
+
+// AbortableWriteCloser is an io.WriteCloser whose writes can be abandoned before they take effect.
+type AbortableWriteCloser interface {
+	io.WriteCloser
+
+	// Abort discards everything written so far, leaving the target untouched.
+	Abort() error
+}
+
+type atomicWriteCloser struct {
+	path    *Path
+	target  *Path
+	temp    string
+	existed bool
+	written int
+	file    File
+	err     error
+}
+
+// WriteCloserAtomic returns a writer that writes to a temporary sibling of p, and renames it over p when closed. If a
+// write fails, or Abort is called, the temporary file is removed and p is left untouched. As with WriteCloser, nothing
+// happens if nothing is written. If p is a symlink, its target is replaced, and the link is left in place.
+func (p *Path) WriteCloserAtomic() AbortableWriteCloser { return &atomicWriteCloser{path: p} }
+
+func (p *Path) WriteFromAtomic(reader io.Reader) (err error) {
+	writer := p.WriteCloserAtomic()
+	_, err = io.Copy(writer, reader)
+	if err == nil {
+		err = writer.Close()
+	} else {
+		_ = writer.Abort()
+	}
+	return
+}
+func (p *Path) MustWriteFromAtomic(reader io.Reader) { must(p.WriteFromAtomic(reader)) }
+func (p *Path) WriteBytesAtomic(b []byte) error      { return p.WriteFromAtomic(bytes.NewReader(b)) }
+func (p *Path) MustWriteBytesAtomic(b []byte)        { must(p.WriteBytesAtomic(b)) }
+func (p *Path) WriteStringAtomic(s string) error     { return p.WriteBytesAtomic([]byte(s)) }
+func (p *Path) MustWriteStringAtomic(s string)       { must(p.WriteStringAtomic(s)) }
+
+func (a *atomicWriteCloser) Write(p []byte) (n int, err error) {
+	if a.err != nil {
+		return 0, a.err
+	}
+	if len(p) == 0 {
+		return
+	}
+	if a.file == nil {
+		if err = a.open(); err != nil {
+			a.err = err
+			return
+		}
+	}
+	n, err = a.file.Write(p)
+	a.written += n
+	if err != nil {
+		a.err = err
+	}
+	return
+}
+
+func (a *atomicWriteCloser) open() (err error) {
+	mode := os.FileMode(0644)
+	a.target = a.path
+	if resolved, err := a.path.EvalSymlinks(); err == nil {
+		a.target = resolved
+	}
+	if stat, err := a.target.StatFollowingLinks(); err == nil && !stat.IsDir() {
+		a.existed = true
+		mode = stat.Mode().Perm()
+	}
+	if err = a.target.Parent().Make(); err != nil {
+		return
+	}
+	suffix := make([]byte, 6)
+	if _, err = rand.Read(suffix); err != nil {
+		return
+	}
+	a.temp = a.target.Parent().Join("." + a.target.Base() + "." + hex.EncodeToString(suffix) + ".tmp").path
+	a.file, err = a.path.tree.sys.OpenFile(a.temp, os.O_CREATE|os.O_EXCL|os.O_RDWR, mode)
+	return
+}
+
+func (a *atomicWriteCloser) Close() error {
+	if a.err != nil {
+		_ = a.Abort()
+		return a.err
+	}
+	file := a.file
+	if file == nil {
+		return nil
+	}
+	a.file = nil
+	sys := a.path.tree.sys
+	if err := file.Close(); err != nil {
+		_ = sys.Remove(a.temp)
+		return err
+	}
+	if err := sys.Rename(a.temp, a.target.path); err != nil {
+		_ = sys.Remove(a.temp)
+		return err
+	}
+	if a.existed {
+		a.path.tree.dispatch(RewriteFileEvent{newFileEvent(a.path, int64(a.written))})
+	} else {
+		a.path.tree.dispatch(CreateFileEvent{newFileEvent(a.path, int64(a.written))})
+	}
+	return nil
+}
+
+func (a *atomicWriteCloser) Abort() error {
+	file := a.file
+	if file == nil {
+		return nil
+	}
+	a.file = nil
+	_ = file.Close()
+	return a.path.tree.sys.Remove(a.temp)
+}
@@ -0,0 +1,56 @@
+package paths_test
+
+import (
+	"errors"
+	"fmt"
+	. "github.com/hx/golib/paths"
+	. "github.com/hx/golib/testing"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPath_WriteFromAtomic(t *testing.T) {
+	eachSystem(t, func(t *testing.T, tree *Tree, dir *Path) {
+		var events []string
+		defer tree.Subscribe(func(event Event) {
+			events = append(events, fmt.Sprintf("%T %s", event, relName(dir, event.Path())))
+		})()
+
+		file := dir.Join("out", "file")
+		Ok(t, file.WriteStringAtomic("first"))
+		Ok(t, file.WriteStringAtomic("second"))
+		Equals(t, "second", file.MustReadString())
+
+		failure := errors.New("read failed")
+		err := file.WriteFromAtomic(io.MultiReader(strings.NewReader("partial"), &failingReader{failure}))
+		Equals(t, failure, err)
+		Equals(t, "second", file.MustReadString())
+
+		Equals(t, Paths{file}, file.Parent().MustChildren())
+		Equals(t, []string{
+			"paths.CreateFileEvent out/file",
+			"paths.RewriteFileEvent out/file",
+		}, events)
+	})
+}
+
+func TestPath_WriteFromAtomic_Symlink(t *testing.T) {
+	eachSystem(t, func(t *testing.T, tree *Tree, dir *Path) {
+		file, link := dir.Join("real", "file"), dir.Join("link")
+		file.MustWriteString("first")
+		file.MustChmod(0600)
+		file.MustSymlinkTo(link)
+
+		Ok(t, link.WriteStringAtomic("second"))
+		Assert(t, link.IsSymlink(), "expected link to remain a symlink")
+		Equals(t, "second", file.MustReadString())
+		Equals(t, os.FileMode(0600), file.MustStat().Mode().Perm())
+		Equals(t, Paths{file}, file.Parent().MustChildren())
+	})
+}
+
+type failingReader struct{ err error }
+
+func (f *failingReader) Read([]byte) (int, error) { return 0, f.err }
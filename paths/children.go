@@ -1,7 +1,7 @@
 package paths
 
 func (p *Path) Children() (paths Paths, err error) {
-	stat, err := p.Stat()
+	stat, err := p.StatFollowingLinks()
 	if err != nil {
 		return
 	}
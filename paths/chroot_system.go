@@ -0,0 +1,285 @@
+package paths
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ChrootSystem confines every operation to a base directory of another System. Paths keep their meaning on the
+// underlying system, but any path outside the base directory, or any path that would leave it by following a symlink,
+// is rejected with ErrEscapesRoot.
+//
+// Symlinks are resolved by ChrootSystem before each operation, so the guarantee holds as long as nothing outside the
+// ChrootSystem changes the contents of the base directory while an operation is in progress.
+type ChrootSystem struct {
+	sys  System
+	base string
+}
+
+// NewChrootSystem returns a System confined to base, which should be an absolute path on sys.
+func NewChrootSystem(sys System, base string) *ChrootSystem {
+	return &ChrootSystem{sys, filepath.Clean(base)}
+}
+
+// Root returns the base directory.
+func (c *ChrootSystem) Root() string { return c.base }
+
+func (c *ChrootSystem) Join(elem ...string) string       { return c.sys.Join(elem...) }
+func (c *ChrootSystem) CurrentUser() (*user.User, error) { return c.sys.CurrentUser() }
+func (c *ChrootSystem) SupportsSymlinks() bool           { return c.sys.SupportsSymlinks() }
+func (c *ChrootSystem) FoldsCase() bool                  { return foldsCase(c.sys) }
+
+// Getwd returns the working directory of the underlying system if it's inside the base directory, or the base
+// directory otherwise.
+func (c *ChrootSystem) Getwd() (dir string, err error) {
+	if dir, err = c.sys.Getwd(); err == nil && c.contains(dir) {
+		return
+	}
+	return c.base, nil
+}
+
+func (c *ChrootSystem) Chmod(name string, mode os.FileMode) error {
+	resolved, err := c.resolve(name, true)
+	if err != nil {
+		return pathError("chmod", name, err)
+	}
+	return c.sys.Chmod(resolved, mode)
+}
+
+func (c *ChrootSystem) Chown(name string, uid, gid int) error {
+	resolved, err := c.resolve(name, true)
+	if err != nil {
+		return pathError("chown", name, err)
+	}
+	return c.sys.Chown(resolved, uid, gid)
+}
+
+func (c *ChrootSystem) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	resolved, err := c.resolve(name, true)
+	if err != nil {
+		return pathError("chtimes", name, err)
+	}
+	return c.sys.Chtimes(resolved, atime, mtime)
+}
+
+func (c *ChrootSystem) Glob(pattern string) (matches []string, err error) {
+	if !c.contains(filepath.Clean(pattern)) {
+		return nil, pathError("glob", pattern, ErrEscapesRoot)
+	}
+	all, err := c.sys.Glob(pattern)
+	if err != nil {
+		return
+	}
+	for _, match := range all {
+		if _, err := c.resolve(match, false); err == nil {
+			matches = append(matches, match)
+		}
+	}
+	return
+}
+
+func (c *ChrootSystem) Lchown(name string, uid, gid int) error {
+	resolved, err := c.resolve(name, false)
+	if err != nil {
+		return pathError("lchown", name, err)
+	}
+	return c.sys.Lchown(resolved, uid, gid)
+}
+
+func (c *ChrootSystem) Link(oldname, newname string) error {
+	resolvedOld, err := c.resolve(oldname, false)
+	if err != nil {
+		return linkError("link", oldname, newname, err)
+	}
+	resolvedNew, err := c.resolve(newname, false)
+	if err != nil {
+		return linkError("link", oldname, newname, err)
+	}
+	return c.sys.Link(resolvedOld, resolvedNew)
+}
+
+func (c *ChrootSystem) Lstat(name string) (os.FileInfo, error) {
+	resolved, err := c.resolve(name, false)
+	if err != nil {
+		return nil, pathError("lstat", name, err)
+	}
+	return c.sys.Lstat(resolved)
+}
+
+func (c *ChrootSystem) Stat(name string) (os.FileInfo, error) {
+	resolved, err := c.resolve(name, true)
+	if err != nil {
+		return nil, pathError("stat", name, err)
+	}
+	info, err := c.sys.Lstat(resolved)
+	if err != nil {
+		return nil, err
+	}
+	if base := filepath.Base(name); info.Name() != base {
+		return renamedInfo{info, base}, nil
+	}
+	return info, nil
+}
+
+func (c *ChrootSystem) MkdirAll(path string, perm os.FileMode) error {
+	resolved, err := c.resolve(path, true)
+	if err != nil {
+		return pathError("mkdir", path, err)
+	}
+	return c.sys.MkdirAll(resolved, perm)
+}
+
+func (c *ChrootSystem) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	resolved, err := c.resolve(name, true)
+	if err != nil {
+		return nil, pathError("open", name, err)
+	}
+	return c.sys.OpenFile(resolved, flag, perm)
+}
+
+func (c *ChrootSystem) ReadDir(name string) ([]os.DirEntry, error) {
+	resolved, err := c.resolve(name, true)
+	if err != nil {
+		return nil, pathError("readdir", name, err)
+	}
+	return c.sys.ReadDir(resolved)
+}
+
+func (c *ChrootSystem) ReadFile(name string) ([]byte, error) {
+	resolved, err := c.resolve(name, true)
+	if err != nil {
+		return nil, pathError("open", name, err)
+	}
+	return c.sys.ReadFile(resolved)
+}
+
+func (c *ChrootSystem) Readlink(name string) (string, error) {
+	resolved, err := c.resolve(name, false)
+	if err != nil {
+		return "", pathError("readlink", name, err)
+	}
+	return c.sys.Readlink(resolved)
+}
+
+func (c *ChrootSystem) Remove(name string) error {
+	resolved, err := c.resolve(name, false)
+	if err == nil && resolved == c.base {
+		err = ErrNotWritable
+	}
+	if err != nil {
+		return pathError("remove", name, err)
+	}
+	return c.sys.Remove(resolved)
+}
+
+func (c *ChrootSystem) RemoveAll(path string) error {
+	resolved, err := c.resolve(path, false)
+	if err == nil && resolved == c.base {
+		err = ErrNotWritable
+	}
+	if err != nil {
+		return pathError("remove", path, err)
+	}
+	return c.sys.RemoveAll(resolved)
+}
+
+func (c *ChrootSystem) Rename(oldpath, newpath string) error {
+	resolvedOld, err := c.resolve(oldpath, false)
+	if err != nil {
+		return linkError("rename", oldpath, newpath, err)
+	}
+	resolvedNew, err := c.resolve(newpath, false)
+	if err == nil && (resolvedOld == c.base || resolvedNew == c.base) {
+		err = ErrNotWritable
+	}
+	if err != nil {
+		return linkError("rename", oldpath, newpath, err)
+	}
+	return c.sys.Rename(resolvedOld, resolvedNew)
+}
+
+// Symlink creates a symlink at newname. Targets that lead outside the base directory are rejected, though this can
+// only be determined lexically; targets that leave the base directory by way of other symlinks are rejected when
+// they're followed.
+func (c *ChrootSystem) Symlink(oldname, newname string) error {
+	resolved, err := c.resolve(newname, false)
+	if err != nil {
+		return linkError("symlink", oldname, newname, err)
+	}
+	target := oldname
+	if !isAbs(target) {
+		target = filepath.Join(filepath.Dir(resolved), target)
+	}
+	if !c.contains(filepath.Clean(target)) {
+		return linkError("symlink", oldname, newname, ErrEscapesRoot)
+	}
+	return c.sys.Symlink(oldname, resolved)
+}
+
+// contains reports whether the clean path name is the base directory, or inside it.
+func (c *ChrootSystem) contains(name string) bool {
+	if name == c.base {
+		return true
+	}
+	prefix := c.base
+	if !strings.HasSuffix(prefix, string(os.PathSeparator)) {
+		prefix += string(os.PathSeparator)
+	}
+	return strings.HasPrefix(name, prefix)
+}
+
+// resolve replaces every symlink in name with its target, failing with ErrEscapesRoot if name, or any target, leaves
+// the base directory. The last component of name is only resolved if follow is true.
+func (c *ChrootSystem) resolve(name string, follow bool) (string, error) {
+	name = filepath.Clean(name)
+	if !c.contains(name) {
+		return "", ErrEscapesRoot
+	}
+	var (
+		resolved = c.base
+		pending  = segments(name[len(c.base):])
+		hops     int
+	)
+	for len(pending) > 0 {
+		part := pending[0]
+		pending = pending[1:]
+		switch part {
+		case ".":
+			continue
+		case "..":
+			if resolved == c.base {
+				return "", ErrEscapesRoot
+			}
+			resolved = filepath.Dir(resolved)
+			continue
+		}
+		next := filepath.Join(resolved, part)
+		if len(pending) == 0 && !follow {
+			return next, nil
+		}
+		info, err := c.sys.Lstat(next)
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+		if hops++; hops > maxLinkHops {
+			return "", ErrLinkLoop
+		}
+		target, err := c.sys.Readlink(next)
+		if err != nil {
+			return "", err
+		}
+		if isAbs(target) {
+			target = filepath.Clean(target)
+			if !c.contains(target) {
+				return "", ErrEscapesRoot
+			}
+			resolved, target = c.base, target[len(c.base):]
+		}
+		pending = append(segments(target), pending...)
+	}
+	return resolved, nil
+}
@@ -0,0 +1,53 @@
+package paths_test
+
+import (
+	"errors"
+	. "github.com/hx/golib/paths"
+	. "github.com/hx/golib/testing"
+	"testing"
+)
+
+func TestChrootSystem(t *testing.T) {
+	for _, sys := range []System{LocalSystem, NewVirtualSystem()} {
+		tree := NewTreeWithSystem(sys)
+		dir := tree.Path
+		if sys == LocalSystem {
+			dir = tree.Join(t.TempDir())
+		}
+		dir.Join("outside", "secret").MustWriteString("secret")
+		dir.Join("ws", "sub", "file").MustWriteString("file")
+		if sys.SupportsSymlinks() {
+			dir.Join("outside").MustSymlinkTo(dir.Join("ws", "out"))
+			dir.Join("ws", "sub").MustSymlinkTo(dir.Join("ws", "in"))
+			Ok(t, sys.Symlink("../../outside", dir.Join("ws", "sub", "up").String()))
+		}
+
+		chroot := NewChrootSystem(sys, dir.Join("ws").String())
+		ws := NewTreeWithSystem(chroot)
+		escapes := func(err error) {
+			t.Helper()
+			Assert(t, errors.Is(err, ErrEscapesRoot), "expected ErrEscapesRoot, got %v", err)
+		}
+		Equals(t, dir.Join("ws").String(), ws.String())
+
+		Equals(t, "file", ws.Join("sub", "file").MustReadString())
+		_, err := ws.Join("..", "outside", "secret").ReadString()
+		escapes(err)
+
+		ws.Join("new", "file").MustWriteString("new")
+		Equals(t, "new", dir.Join("ws", "new", "file").MustReadString())
+		escapes(ws.Join("..", "escaped").WriteString("oops"))
+		Equals(t, false, dir.Join("escaped").Exists())
+
+		if sys.SupportsSymlinks() {
+			Equals(t, "file", ws.Join("in", "file").MustReadString())
+			for _, p := range []*Path{ws.Join("out", "secret"), ws.Join("sub", "up", "secret")} {
+				_, err = p.ReadString()
+				escapes(err)
+			}
+			Equals(t, true, ws.Join("out").Exists())
+			escapes(chroot.Symlink("../outside", ws.Join("link").String()))
+			Equals(t, 0, len(ws.MustGlob("out/*")))
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package paths
+
+import (
+	"os"
+	"strings"
+)
+
+// CopyTreeTo copies p and all its descendants to target. Directories are created with the modes of their sources,
+// files are only written if their contents differ, and symlinks are recreated with the same (unresolved) targets.
+// A CopyEvent or CopyOverEvent is dispatched for each entry that is created or overwritten.
+func (p *Path) CopyTreeTo(target *Path) error {
+	if target.tree.sys == p.tree.sys && (target.path == p.path || p.isAncestorOf(target)) {
+		return ErrInvalid
+	}
+	type dirMode struct {
+		dir  *Path
+		mode os.FileMode
+	}
+	var dirModes []dirMode
+	err := p.Walk(func(path *Path, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		dest := target.Join(p.nameOf(path))
+		mode := info.Mode()
+		switch {
+		case mode.IsDir():
+			dirModes = append(dirModes, dirMode{dest, mode.Perm()})
+			return path.copyDirTo(dest)
+		case mode&os.ModeSymlink != 0:
+			return path.copyLinkTo(dest)
+		case mode.IsRegular():
+			return path.copyFileTo(dest, mode.Perm())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// Modes are applied last, and deepest first, so read-only directories can still be populated.
+	for i := len(dirModes) - 1; i >= 0; i-- {
+		if err = dirModes[i].dir.chmodUnlessEqual(dirModes[i].mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Path) MustCopyTreeTo(target *Path) { must(p.CopyTreeTo(target)) }
+
+func (p *Path) copyDirTo(target *Path) error {
+	stat, err := target.Stat()
+	if err == nil && stat.IsDir() {
+		return nil
+	}
+	existed := err == nil
+	if existed {
+		if err = target.Delete(); err != nil {
+			return err
+		}
+	}
+	if err = target.tree.sys.MkdirAll(target.path, 0755); err != nil {
+		return err
+	}
+	if existed {
+		p.tree.dispatch(CopyOverEvent{newTargetEvent(p, target)})
+	} else {
+		p.tree.dispatch(CopyEvent{newTargetEvent(p, target)})
+	}
+	return nil
+}
+
+func (p *Path) copyFileTo(target *Path, mode os.FileMode) error {
+	if stat, err := target.Stat(); err == nil && !stat.Mode().IsRegular() {
+		if err = target.Delete(); err != nil {
+			return err
+		}
+	}
+	if err := p.copyTo(target, mode); err != nil {
+		return err
+	}
+	return target.chmodUnlessEqual(mode)
+}
+
+func (p *Path) copyLinkTo(target *Path) error {
+	sys := p.tree.sys
+	linkTarget, err := sys.Readlink(p.path)
+	if err != nil {
+		return err
+	}
+	stat, err := target.Stat()
+	existed := err == nil
+	if existed {
+		if stat.Mode()&os.ModeSymlink != 0 {
+			if current, err := target.tree.sys.Readlink(target.path); err == nil && current == linkTarget {
+				return nil
+			}
+		}
+		if err = target.Delete(); err != nil {
+			return err
+		}
+	}
+	if err = target.tree.sys.Symlink(linkTarget, target.path); err != nil {
+		return err
+	}
+	if existed {
+		p.tree.dispatch(CopyOverEvent{newTargetEvent(p, target)})
+	} else {
+		p.tree.dispatch(CopyEvent{newTargetEvent(p, target)})
+	}
+	return nil
+}
+
+func (p *Path) chmodUnlessEqual(mode os.FileMode) error {
+	stat, err := p.Stat()
+	if err != nil || stat.Mode().Perm() == mode {
+		return err
+	}
+	return p.Chmod(mode)
+}
+
+// isAncestorOf returns true if other is a descendant of p. Both paths are compared lexically.
+func (p *Path) isAncestorOf(other *Path) bool {
+	prefix := p.path
+	if !strings.HasSuffix(prefix, string(os.PathSeparator)) {
+		prefix += string(os.PathSeparator)
+	}
+	return strings.HasPrefix(other.path, prefix)
+}
+
+// nameOf returns the path of descendant relative to p. It assumes descendant was derived from p using Join.
+func (p *Path) nameOf(descendant *Path) string {
+	return strings.TrimPrefix(descendant.path[len(p.path):], string(os.PathSeparator))
+}
@@ -0,0 +1,65 @@
+package paths_test
+
+import (
+	"fmt"
+	. "github.com/hx/golib/paths"
+	. "github.com/hx/golib/testing"
+	"os"
+	"testing"
+)
+
+func TestPath_CopyTreeTo(t *testing.T) {
+	eachSystem(t, func(t *testing.T, tree *Tree, dir *Path) {
+		src := dir.Join("src")
+		src.Join("a", "one").MustWriteString("1")
+		src.Join("a", "two").MustWriteString("2")
+		src.Join("a", "two").MustChmod(0600)
+		src.Join("b").MustMakeMode(0755).MustChmod(0750)
+		if LocalSystem.SupportsSymlinks() {
+			src.Join("a", "one").MustSymlinkTo(src.Join("b", "link"))
+		}
+
+		var events []string
+		unsubscribe := tree.Subscribe(func(event Event) {
+			if event, ok := event.(TargetEvent); ok {
+				events = append(events, fmt.Sprintf("%T %s", event, relName(src, event.Path())))
+			}
+		})
+		defer unsubscribe()
+
+		dst := dir.Join("dst")
+		Ok(t, src.CopyTreeTo(dst))
+
+		Equals(t, "1", dst.Join("a", "one").MustReadString())
+		Equals(t, "2", dst.Join("a", "two").MustReadString())
+		Equals(t, os.FileMode(0600), dst.Join("a", "two").MustStat().Mode().Perm())
+		Equals(t, os.FileMode(0750), dst.Join("b").MustStat().Mode().Perm())
+		expected := []string{
+			"paths.CopyEvent ",
+			"paths.CopyEvent a",
+			"paths.CopyEvent a/one",
+			"paths.CopyEvent a/two",
+			"paths.CopyEvent b",
+		}
+		if LocalSystem.SupportsSymlinks() {
+			Equals(t, src.Join("a", "one").String(), dst.Join("b", "link").MustReadLink().String())
+			expected = append(expected, "paths.CopyEvent b/link")
+		}
+		Equals(t, expected, events)
+
+		events = nil
+		src.Join("a", "two").MustWriteString("changed")
+		Ok(t, src.CopyTreeTo(dst))
+		Equals(t, "changed", dst.Join("a", "two").MustReadString())
+		Equals(t, []string{"paths.CopyOverEvent a/two"}, events)
+
+		events = nil
+		dst.Join("c").MustWriteString("file")
+		src.Join("c", "file").MustWriteString("nested")
+		Ok(t, src.CopyTreeTo(dst))
+		Equals(t, "nested", dst.Join("c", "file").MustReadString())
+		Equals(t, []string{"paths.CopyOverEvent c", "paths.CopyEvent c/file"}, events)
+
+		Equals(t, ErrInvalid, src.CopyTreeTo(src.Join("a", "nested")))
+	})
+}
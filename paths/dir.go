@@ -22,7 +22,7 @@ func (p *Path) removeEmptyDirs(recursive bool) (removed Paths, err error) {
 	}
 	var empty bool
 	for _, child := range children {
-		if !child.IsDir() {
+		if stat, err := child.Stat(); err != nil || !stat.IsDir() {
 			continue
 		}
 		if recursive {
@@ -37,10 +37,10 @@ func (p *Path) removeEmptyDirs(recursive bool) (removed Paths, err error) {
 		if !empty || err != nil {
 			continue
 		}
-		err = child.Delete()
-		if err == nil {
-			removed = append(removed, child)
+		if err = child.Delete(); err != nil {
+			return
 		}
+		removed = append(removed, child)
 	}
 	return
 }
@@ -55,10 +55,9 @@ func (p *Path) removeEmptyDirsAndSelf(recursive bool) (removed Paths, err error)
 		return
 	}
 	if empty {
-		err = p.Delete()
-	}
-	if err == nil {
-		removed = append(removed, p)
+		if err = p.Delete(); err == nil {
+			removed = append(removed, p)
+		}
 	}
 	return
 }
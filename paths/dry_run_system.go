@@ -0,0 +1,188 @@
+package paths
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// DryRunOp is an operation that a DryRunSystem was asked to perform.
+type DryRunOp struct {
+	// The name of the System method, e.g. "MkdirAll".
+	Method string
+
+	// The path being operated on. For Rename, this is the old path, and for Link and Symlink, it's the path of the new
+	// link.
+	Path string
+
+	// The new path for Rename, and the existing path for Link and Symlink.
+	Target string
+
+	// The mode passed to Chmod, MkdirAll or OpenFile.
+	Mode os.FileMode
+
+	// The flags passed to OpenFile.
+	Flag int
+
+	// The modification time passed to Chtimes.
+	Time time.Time
+
+	// The owner passed to Chown or Lchown.
+	Uid int
+	Gid int
+
+	// The number of bytes written to a file opened with OpenFile.
+	Size int64
+}
+
+func (o DryRunOp) String() string {
+	switch o.Method {
+	case "Rename", "Link", "Symlink":
+		return fmt.Sprintf("%s %s %s", o.Method, o.Path, o.Target)
+	case "Chmod", "MkdirAll":
+		return fmt.Sprintf("%s %s %#o", o.Method, o.Path, o.Mode.Perm())
+	case "Chown", "Lchown":
+		return fmt.Sprintf("%s %s %d:%d", o.Method, o.Path, o.Uid, o.Gid)
+	case "Chtimes":
+		return fmt.Sprintf("%s %s %s", o.Method, o.Path, o.Time.Format(time.RFC3339))
+	case "OpenFile":
+		return fmt.Sprintf("%s %s %#o (%d bytes written)", o.Method, o.Path, o.Mode.Perm(), o.Size)
+	}
+	return o.Method + " " + o.Path
+}
+
+// DryRunSystem wraps another System. Calls that would modify it succeed without doing anything, and are recorded in a
+// log instead. Calls that don't modify the system are passed through, so they don't reflect any recorded operations.
+//
+// Files opened for writing discard everything written to them, and read as empty.
+type DryRunSystem struct {
+	System
+	log   []*DryRunOp
+	mutex sync.Mutex
+}
+
+func NewDryRunSystem(sys System) *DryRunSystem { return &DryRunSystem{System: sys} }
+
+func (d *DryRunSystem) FoldsCase() bool { return foldsCase(d.System) }
+
+// Log returns the operations recorded so far, in the order they were requested.
+func (d *DryRunSystem) Log() []DryRunOp {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	log := make([]DryRunOp, len(d.log))
+	for i, op := range d.log {
+		log[i] = *op
+	}
+	return log
+}
+
+// Reset clears the log.
+func (d *DryRunSystem) Reset() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.log = nil
+}
+
+func (d *DryRunSystem) record(op *DryRunOp) *DryRunOp {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.log = append(d.log, op)
+	return op
+}
+
+func (d *DryRunSystem) Chmod(name string, mode os.FileMode) error {
+	d.record(&DryRunOp{Method: "Chmod", Path: name, Mode: mode})
+	return nil
+}
+
+func (d *DryRunSystem) Chown(name string, uid, gid int) error {
+	d.record(&DryRunOp{Method: "Chown", Path: name, Uid: uid, Gid: gid})
+	return nil
+}
+
+func (d *DryRunSystem) Lchown(name string, uid, gid int) error {
+	d.record(&DryRunOp{Method: "Lchown", Path: name, Uid: uid, Gid: gid})
+	return nil
+}
+
+func (d *DryRunSystem) Link(oldname, newname string) error {
+	d.record(&DryRunOp{Method: "Link", Path: newname, Target: oldname})
+	return nil
+}
+
+func (d *DryRunSystem) Chtimes(name string, _ time.Time, mtime time.Time) error {
+	d.record(&DryRunOp{Method: "Chtimes", Path: name, Time: mtime})
+	return nil
+}
+
+func (d *DryRunSystem) MkdirAll(path string, perm os.FileMode) error {
+	d.record(&DryRunOp{Method: "MkdirAll", Path: path, Mode: perm})
+	return nil
+}
+
+func (d *DryRunSystem) Remove(name string) error {
+	d.record(&DryRunOp{Method: "Remove", Path: name})
+	return nil
+}
+
+func (d *DryRunSystem) RemoveAll(path string) error {
+	d.record(&DryRunOp{Method: "RemoveAll", Path: path})
+	return nil
+}
+
+func (d *DryRunSystem) Rename(oldpath, newpath string) error {
+	d.record(&DryRunOp{Method: "Rename", Path: oldpath, Target: newpath})
+	return nil
+}
+
+func (d *DryRunSystem) Symlink(oldname, newname string) error {
+	d.record(&DryRunOp{Method: "Symlink", Path: newname, Target: oldname})
+	return nil
+}
+
+func (d *DryRunSystem) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	if !isWriteFlag(flag) {
+		return d.System.OpenFile(name, flag, perm)
+	}
+	return &dryRunFile{sys: d, op: d.record(&DryRunOp{Method: "OpenFile", Path: name, Mode: perm, Flag: flag})}, nil
+}
+
+type dryRunFile struct {
+	sys    *DryRunSystem
+	op     *DryRunOp
+	offset int64
+}
+
+func (f *dryRunFile) Read([]byte) (int, error) { return 0, io.EOF }
+func (f *dryRunFile) Close() error             { return nil }
+
+func (f *dryRunFile) Write(b []byte) (int, error) {
+	f.sys.mutex.Lock()
+	defer f.sys.mutex.Unlock()
+	f.offset += int64(len(b))
+	if f.offset > f.op.Size {
+		f.op.Size = f.offset
+	}
+	return len(b), nil
+}
+
+func (f *dryRunFile) Seek(offset int64, whence int) (int64, error) {
+	f.sys.mutex.Lock()
+	defer f.sys.mutex.Unlock()
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.op.Size
+	default:
+		return 0, fmt.Errorf("%d is not valid for argument whence", whence)
+	}
+	if offset < 0 {
+		return 0, ErrInvalid
+	}
+	f.offset = offset
+	return offset, nil
+}
@@ -0,0 +1,34 @@
+package paths_test
+
+import (
+	. "github.com/hx/golib/paths"
+	. "github.com/hx/golib/testing"
+	"testing"
+)
+
+func TestDryRunSystem(t *testing.T) {
+	sys := NewVirtualSystem()
+	NewTreeWithSystem(sys).Join("file").MustWriteString("contents")
+	dryRun := NewDryRunSystem(sys)
+	tree := NewTreeWithSystem(dryRun)
+
+	tree.Join("dir", "new").MustWriteString("hello")
+	tree.Join("file").MustDelete()
+	tree.Join("other").MustChmod(0600)
+
+	var log []string
+	for _, op := range dryRun.Log() {
+		log = append(log, op.String())
+	}
+	Equals(t, []string{
+		"MkdirAll " + tree.Join("dir").String() + " 0755",
+		"OpenFile " + tree.Join("dir", "new").String() + " 0644 (5 bytes written)",
+		"Remove " + tree.Join("file").String(),
+		"Chmod " + tree.Join("other").String() + " 0600",
+	}, log)
+	Equals(t, "contents", tree.Join("file").MustReadString())
+	Equals(t, false, tree.Join("dir").Exists())
+
+	dryRun.Reset()
+	Equals(t, 0, len(dryRun.Log()))
+}
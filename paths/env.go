@@ -0,0 +1,30 @@
+package paths
+
+import "os"
+
+// envSystem is implemented by systems that have environment variables, such as LocalSystem, whose environment is that
+// of the current process, and VirtualSystem, which has its own.
+type envSystem interface {
+	LookupEnv(key string) (string, bool)
+}
+
+func (l local) LookupEnv(key string) (string, bool) { return os.LookupEnv(key) }
+
+// LookupEnv returns the value of an environment variable of the tree's System, and whether it's set. Systems without
+// environment variables have none set.
+func (t *Tree) LookupEnv(key string) (string, bool) {
+	if sys, ok := t.sys.(envSystem); ok {
+		return sys.LookupEnv(key)
+	}
+	return "", false
+}
+
+// Getenv returns the value of an environment variable of the tree's System, or an empty string if it's not set.
+func (t *Tree) Getenv(key string) string {
+	value, _ := t.LookupEnv(key)
+	return value
+}
+
+// ExpandEnv replaces ${var} or $var in s with the values of the tree's environment variables, and returns the result
+// joined to p.
+func (p *Path) ExpandEnv(s string) *Path { return p.Join(os.Expand(s, p.tree.Getenv)) }
@@ -1,9 +1,39 @@
 package paths
 
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
 type Error string
 
 func (e Error) Error() string { return string(e) }
 
+// Is reports whether e is equivalent to target, so that errors from VirtualSystem can be tested against the same
+// errors as those from LocalSystem, e.g. errors.Is(err, fs.ErrNotExist).
+func (e Error) Is(target error) bool {
+	switch e {
+	case ErrPathNotFound, ErrBrokenLink:
+		return target == fs.ErrNotExist || target == syscall.ENOENT
+	case ErrFileExists:
+		return target == fs.ErrExist || target == syscall.EEXIST
+	case ErrNotWritable, ErrEscapesRoot:
+		return target == fs.ErrPermission
+	case ErrPermission:
+		return target == fs.ErrPermission || target == syscall.EACCES
+	case ErrInvalid, ErrNonLink:
+		return target == fs.ErrInvalid || target == syscall.EINVAL
+	case ErrDirectory:
+		return target == syscall.EISDIR
+	case ErrNonDirectory:
+		return target == syscall.ENOTDIR
+	case ErrLinkLoop:
+		return target == syscall.ELOOP
+	}
+	return false
+}
+
 const (
 	ErrDirectory    Error = "file is a directory"
 	ErrNonDirectory Error = "not a directory"
@@ -13,4 +43,25 @@ const (
 	ErrBrokenLink   Error = "broken link"
 	ErrNotWritable  Error = "not writable"
 	ErrInvalid      Error = "invalid"
+	ErrLinkLoop     Error = "too many levels of symbolic links"
+	ErrEscapesRoot  Error = "path escapes root"
+	ErrUnsupported  Error = "not supported"
+	ErrLocked       Error = "path is locked"
+	ErrPermission   Error = "permission denied"
 )
+
+// pathError wraps err in an *fs.PathError, unless it's nil or already an *fs.PathError.
+func pathError(op, path string, err error) error {
+	if _, ok := err.(*fs.PathError); ok || err == nil {
+		return err
+	}
+	return &fs.PathError{Op: op, Path: path, Err: err}
+}
+
+// linkError wraps err in an *os.LinkError, unless it's nil.
+func linkError(op, oldname, newname string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &os.LinkError{Op: op, Old: oldname, New: newname, Err: err}
+}
@@ -0,0 +1,77 @@
+package paths_test
+
+import (
+	"errors"
+	. "github.com/hx/golib/paths"
+	. "github.com/hx/golib/testing"
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestError_Is(t *testing.T) {
+	Assert(t, errors.Is(ErrPathNotFound, fs.ErrNotExist), "ErrPathNotFound should be fs.ErrNotExist")
+	Assert(t, errors.Is(ErrBrokenLink, fs.ErrNotExist), "ErrBrokenLink should be fs.ErrNotExist")
+	Assert(t, errors.Is(ErrFileExists, fs.ErrExist), "ErrFileExists should be fs.ErrExist")
+	Assert(t, errors.Is(ErrNotWritable, fs.ErrPermission), "ErrNotWritable should be fs.ErrPermission")
+	Assert(t, errors.Is(ErrNonDirectory, syscall.ENOTDIR), "ErrNonDirectory should be syscall.ENOTDIR")
+	Assert(t, !errors.Is(ErrPathNotFound, fs.ErrExist), "ErrPathNotFound should not be fs.ErrExist")
+}
+
+func TestSystem_Errors(t *testing.T) {
+	eachSystem(t, func(t *testing.T, _ *Tree, dir *Path) {
+		missing := dir.Join("missing")
+		file := dir.Join("file")
+		file.MustWriteString("hello")
+
+		_, err := missing.Stat()
+		Assert(t, errors.Is(err, fs.ErrNotExist), "expected fs.ErrNotExist, got %v", err)
+		var pathErr *fs.PathError
+		Assert(t, errors.As(err, &pathErr), "expected *fs.PathError, got %T", err)
+		Equals(t, "lstat", pathErr.Op)
+		Equals(t, missing.String(), pathErr.Path)
+
+		_, err = missing.ReadString()
+		Assert(t, errors.Is(err, fs.ErrNotExist), "expected fs.ErrNotExist, got %v", err)
+
+		err = file.Rename(dir.Join("missing", "file"))
+		Assert(t, errors.Is(err, fs.ErrNotExist), "expected fs.ErrNotExist, got %v", err)
+		var linkErr *os.LinkError
+		Assert(t, errors.As(err, &linkErr), "expected *os.LinkError, got %T", err)
+	})
+	t.Run("OverlaySystem", testOverlaySystemErrors)
+}
+
+func testOverlaySystemErrors(t *testing.T) {
+	lower := NewVirtualSystem()
+	overlay := NewOverlaySystem(NewVirtualSystem(), lower)
+	tree := NewTreeWithSystem(overlay)
+	file := NewTreeWithSystem(lower).Join("file")
+	file.MustWriteString("hello")
+	pathErr := func(err error, target error, op, path string) {
+		t.Helper()
+		Assert(t, errors.Is(err, target), "expected %v, got %v", target, err)
+		var pathErr *fs.PathError
+		Assert(t, errors.As(err, &pathErr), "expected *fs.PathError, got %T", err)
+		Equals(t, op, pathErr.Op)
+		Equals(t, path, pathErr.Path)
+	}
+
+	_, err := overlay.OpenFile(file.String(), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	pathErr(err, fs.ErrExist, "open", file.String())
+	pathErr(overlay.MkdirAll(file.String(), 0755), syscall.ENOTDIR, "mkdir", file.String())
+	pathErr(tree.Join("file", "child").WriteString("child"), syscall.ENOTDIR, "mkdir", file.String())
+
+	err = overlay.Link(file.String(), file.String())
+	Assert(t, errors.Is(err, fs.ErrExist), "expected fs.ErrExist, got %v", err)
+	var linkErr *os.LinkError
+	Assert(t, errors.As(err, &linkErr), "expected *os.LinkError, got %T", err)
+
+	if overlay.SupportsSymlinks() {
+		loop := tree.Join("loop")
+		loop.MustSymlinkTo(loop)
+		_, err = loop.StatFollowingLinks()
+		pathErr(err, syscall.ELOOP, "stat", loop.String())
+	}
+}
@@ -0,0 +1,316 @@
+package paths
+
+import (
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fault describes calls that a FaultySystem should interfere with, and how.
+type Fault struct {
+	// The name of the System method to match, e.g. "OpenFile", or, for calls made on open files, the name of the File
+	// method prefixed with "File.", e.g. "File.Write". Empty matches every method.
+	Method string
+
+	// The path to match. Calls on the path itself and anything beneath it are matched, and calls made on open files are
+	// matched against the path they were opened with. If Path contains glob metacharacters, it's matched using
+	// filepath.Match instead. Empty matches every path.
+	Path string
+
+	// If non-zero, only the Nth matching call is affected. Matches are counted from 1.
+	Nth int
+
+	// If non-zero, matching calls to File.Write succeed until this many bytes have been written to the file, and then
+	// write as much as they can before failing with Err. Nth is ignored.
+	After int64
+
+	// The error matching calls should return. If nil, matching calls succeed after Delay, unless After is set, in
+	// which case io.ErrShortWrite is returned.
+	Err error
+
+	// How long matching calls should wait before proceeding.
+	Delay time.Duration
+
+	matched int
+}
+
+func (f *Fault) matches(method, path string) bool {
+	if f.Method != "" && f.Method != method {
+		return false
+	}
+	if f.Path == "" {
+		return true
+	}
+	if globHasMeta(f.Path) {
+		matched, _ := filepath.Match(f.Path, path)
+		return matched
+	}
+	prefix := strings.TrimSuffix(f.Path, string(os.PathSeparator)) + string(os.PathSeparator)
+	return path == f.Path || strings.HasPrefix(path, prefix)
+}
+
+// FaultySystem wraps another System, and injects errors, short writes and latency into calls that match its faults.
+// Calls that fail are not passed on to the underlying System.
+type FaultySystem struct {
+	sys    System
+	faults []*Fault
+	mutex  sync.Mutex
+}
+
+func NewFaultySystem(sys System, faults ...*Fault) *FaultySystem {
+	return &FaultySystem{sys: sys, faults: faults}
+}
+
+// AddFault adds a fault, which applies to calls made after it's added.
+func (f *FaultySystem) AddFault(fault *Fault) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.faults = append(f.faults, fault)
+}
+
+// ClearFaults removes all faults.
+func (f *FaultySystem) ClearFaults() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.faults = nil
+}
+
+// inject applies the delays of any faults that match a call, and returns the error of the first one that has one.
+func (f *FaultySystem) inject(method, path string) (err error) {
+	var delay time.Duration
+	f.mutex.Lock()
+	for _, fault := range f.faults {
+		if fault.After != 0 || !fault.matches(method, path) {
+			continue
+		}
+		fault.matched++
+		if fault.Nth != 0 && fault.matched != fault.Nth {
+			continue
+		}
+		delay += fault.Delay
+		if err == nil {
+			err = fault.Err
+		}
+	}
+	f.mutex.Unlock()
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	return
+}
+
+// limit returns the number of bytes that can be written to a file at path that has already had written bytes written
+// to it, and the error to return once that limit is reached. If there's no limit, n is -1.
+func (f *FaultySystem) limit(path string, written int64) (n int64, err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	n = -1
+	for _, fault := range f.faults {
+		if fault.After == 0 || !fault.matches("File.Write", path) {
+			continue
+		}
+		if remaining := fault.After - written; n == -1 || remaining < n {
+			n, err = remaining, fault.Err
+			if n < 0 {
+				n = 0
+			}
+			if err == nil {
+				err = io.ErrShortWrite
+			}
+		}
+	}
+	return
+}
+
+func (f *FaultySystem) Join(elem ...string) string { return f.sys.Join(elem...) }
+func (f *FaultySystem) Root() string               { return f.sys.Root() }
+func (f *FaultySystem) SupportsSymlinks() bool     { return f.sys.SupportsSymlinks() }
+func (f *FaultySystem) FoldsCase() bool            { return foldsCase(f.sys) }
+
+func (f *FaultySystem) Chmod(name string, mode os.FileMode) error {
+	if err := f.inject("Chmod", name); err != nil {
+		return pathError("chmod", name, err)
+	}
+	return f.sys.Chmod(name, mode)
+}
+
+func (f *FaultySystem) Chown(name string, uid, gid int) error {
+	if err := f.inject("Chown", name); err != nil {
+		return pathError("chown", name, err)
+	}
+	return f.sys.Chown(name, uid, gid)
+}
+
+func (f *FaultySystem) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	if err := f.inject("Chtimes", name); err != nil {
+		return pathError("chtimes", name, err)
+	}
+	return f.sys.Chtimes(name, atime, mtime)
+}
+
+func (f *FaultySystem) CurrentUser() (*user.User, error) {
+	if err := f.inject("CurrentUser", ""); err != nil {
+		return nil, err
+	}
+	return f.sys.CurrentUser()
+}
+
+func (f *FaultySystem) Getwd() (dir string, err error) {
+	if err = f.inject("Getwd", ""); err != nil {
+		return "", pathError("getwd", ".", err)
+	}
+	return f.sys.Getwd()
+}
+
+func (f *FaultySystem) Glob(pattern string) (matches []string, err error) {
+	if err = f.inject("Glob", pattern); err != nil {
+		return nil, pathError("glob", pattern, err)
+	}
+	return f.sys.Glob(pattern)
+}
+
+func (f *FaultySystem) Lchown(name string, uid, gid int) error {
+	if err := f.inject("Lchown", name); err != nil {
+		return pathError("lchown", name, err)
+	}
+	return f.sys.Lchown(name, uid, gid)
+}
+
+func (f *FaultySystem) Link(oldname, newname string) error {
+	if err := f.inject("Link", newname); err != nil {
+		return linkError("link", oldname, newname, err)
+	}
+	return f.sys.Link(oldname, newname)
+}
+
+func (f *FaultySystem) Lstat(name string) (os.FileInfo, error) {
+	if err := f.inject("Lstat", name); err != nil {
+		return nil, pathError("lstat", name, err)
+	}
+	return f.sys.Lstat(name)
+}
+
+func (f *FaultySystem) MkdirAll(path string, perm os.FileMode) error {
+	if err := f.inject("MkdirAll", path); err != nil {
+		return pathError("mkdir", path, err)
+	}
+	return f.sys.MkdirAll(path, perm)
+}
+
+func (f *FaultySystem) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	if err := f.inject("OpenFile", name); err != nil {
+		return nil, pathError("open", name, err)
+	}
+	file, err := f.sys.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &faultyFile{sys: f, file: file, path: name}, nil
+}
+
+func (f *FaultySystem) ReadDir(name string) ([]os.DirEntry, error) {
+	if err := f.inject("ReadDir", name); err != nil {
+		return nil, pathError("readdir", name, err)
+	}
+	return f.sys.ReadDir(name)
+}
+
+func (f *FaultySystem) ReadFile(name string) ([]byte, error) {
+	if err := f.inject("ReadFile", name); err != nil {
+		return nil, pathError("open", name, err)
+	}
+	return f.sys.ReadFile(name)
+}
+
+func (f *FaultySystem) Readlink(name string) (string, error) {
+	if err := f.inject("Readlink", name); err != nil {
+		return "", pathError("readlink", name, err)
+	}
+	return f.sys.Readlink(name)
+}
+
+func (f *FaultySystem) Remove(name string) error {
+	if err := f.inject("Remove", name); err != nil {
+		return pathError("remove", name, err)
+	}
+	return f.sys.Remove(name)
+}
+
+func (f *FaultySystem) RemoveAll(path string) error {
+	if err := f.inject("RemoveAll", path); err != nil {
+		return pathError("remove", path, err)
+	}
+	return f.sys.RemoveAll(path)
+}
+
+func (f *FaultySystem) Rename(oldpath, newpath string) error {
+	if err := f.inject("Rename", oldpath); err != nil {
+		return linkError("rename", oldpath, newpath, err)
+	}
+	return f.sys.Rename(oldpath, newpath)
+}
+
+func (f *FaultySystem) Stat(name string) (os.FileInfo, error) {
+	if err := f.inject("Stat", name); err != nil {
+		return nil, pathError("stat", name, err)
+	}
+	return f.sys.Stat(name)
+}
+
+func (f *FaultySystem) Symlink(oldname, newname string) error {
+	if err := f.inject("Symlink", newname); err != nil {
+		return linkError("symlink", oldname, newname, err)
+	}
+	return f.sys.Symlink(oldname, newname)
+}
+
+type faultyFile struct {
+	sys     *FaultySystem
+	file    File
+	path    string
+	written int64
+}
+
+func (f *faultyFile) Read(b []byte) (int, error) {
+	if err := f.sys.inject("File.Read", f.path); err != nil {
+		return 0, pathError("read", f.path, err)
+	}
+	return f.file.Read(b)
+}
+
+func (f *faultyFile) Write(b []byte) (n int, err error) {
+	if err = f.sys.inject("File.Write", f.path); err != nil {
+		return 0, pathError("write", f.path, err)
+	}
+	limit, limitErr := f.sys.limit(f.path, f.written)
+	if limit >= 0 && limit < int64(len(b)) {
+		n, err = f.file.Write(b[:limit])
+		f.written += int64(n)
+		if err == nil {
+			err = pathError("write", f.path, limitErr)
+		}
+		return
+	}
+	n, err = f.file.Write(b)
+	f.written += int64(n)
+	return
+}
+
+func (f *faultyFile) Seek(offset int64, whence int) (int64, error) {
+	if err := f.sys.inject("File.Seek", f.path); err != nil {
+		return 0, pathError("seek", f.path, err)
+	}
+	return f.file.Seek(offset, whence)
+}
+
+func (f *faultyFile) Close() error {
+	if err := f.sys.inject("File.Close", f.path); err != nil {
+		_ = f.file.Close()
+		return pathError("close", f.path, err)
+	}
+	return f.file.Close()
+}
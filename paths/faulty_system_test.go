@@ -0,0 +1,83 @@
+package paths_test
+
+import (
+	"errors"
+	. "github.com/hx/golib/paths"
+	. "github.com/hx/golib/testing"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestFaultySystem_Nth(t *testing.T) {
+	failure := errors.New("failure")
+	sys := NewFaultySystem(NewVirtualSystem())
+	tree := NewTreeWithSystem(sys)
+	sys.AddFault(&Fault{Method: "OpenFile", Path: tree.Join("out").String(), Nth: 3, Err: failure})
+	Ok(t, tree.Join("out", "1").WriteString("1"))
+	Ok(t, tree.Join("elsewhere").WriteString("x"))
+	Ok(t, tree.Join("out", "2").WriteString("2"))
+	err := tree.Join("out", "3").WriteString("3")
+	Assert(t, errors.Is(err, failure), "expected failure, got %v", err)
+	Ok(t, tree.Join("out", "4").WriteString("4"))
+	Equals(t, false, tree.Join("out", "3").Exists())
+}
+
+func TestFaultySystem_After(t *testing.T) {
+	sys := NewFaultySystem(NewVirtualSystem())
+	tree := NewTreeWithSystem(sys)
+	sys.AddFault(&Fault{Method: "File.Write", Path: tree.Join("out").String(), After: 1024, Err: syscall.ENOSPC})
+	file, err := tree.Join("out", "file").Create()
+	Ok(t, err)
+	n, err := file.Write(make([]byte, 1000))
+	Ok(t, err)
+	Equals(t, 1000, n)
+	n, err = file.Write(make([]byte, 1000))
+	Assert(t, errors.Is(err, syscall.ENOSPC), "expected ENOSPC, got %v", err)
+	Equals(t, 24, n)
+	Ok(t, file.Close())
+}
+
+func TestFaultySystem_Delay(t *testing.T) {
+	tree := NewTreeWithSystem(NewFaultySystem(NewVirtualSystem(), &Fault{Method: "Lstat", Delay: 20 * time.Millisecond}))
+	start := time.Now()
+	tree.Join("file").Exists()
+	Assert(t, time.Since(start) >= 20*time.Millisecond, "Lstat should have been delayed")
+}
+
+func TestFaultySystem_ErrorPaths(t *testing.T) {
+	failure := errors.New("failure")
+	sys := NewFaultySystem(NewVirtualSystem())
+	tree := NewTreeWithSystem(sys)
+	tree.Join("source").MustWriteString("contents")
+
+	sys.AddFault(&Fault{Method: "File.Close", Path: tree.Join("target").String(), Err: failure})
+	err := tree.Join("source").CopyTo(tree.Join("target"))
+	Assert(t, errors.Is(err, failure), "expected failure, got %v", err)
+
+	sys.ClearFaults()
+	sys.AddFault(&Fault{Method: "File.Read", Path: tree.Join("source").String(), Err: io.ErrUnexpectedEOF})
+	_, err = tree.Join("source").Sha256Digest()
+	Assert(t, errors.Is(err, io.ErrUnexpectedEOF), "expected io.ErrUnexpectedEOF, got %v", err)
+
+	sys.ClearFaults()
+	tree.Join("dirs", "a").MustMake()
+	tree.Join("dirs", "b").MustMake()
+	tree.Join("dirs", "c").MustMake()
+	sys.AddFault(&Fault{Method: "RemoveAll", Path: tree.Join("dirs", "b").String(), Err: failure})
+	removed, err := tree.Join("dirs").RemoveEmptyDirs()
+	Assert(t, errors.Is(err, failure), "expected failure, got %v", err)
+	Equals(t, Paths{tree.Join("dirs", "a")}, removed)
+	Equals(t, true, tree.Join("dirs", "b").Exists())
+	Equals(t, true, tree.Join("dirs", "c").Exists())
+
+	sys.ClearFaults()
+	full := tree.Join("full")
+	full.Join("empty").MustMake()
+	full.Join("file").MustWriteString("file")
+	removed, err = full.RemoveEmptyDirsAndSelf()
+	Ok(t, err)
+	Equals(t, Paths{full.Join("empty")}, removed)
+	Equals(t, true, full.Exists())
+}
@@ -0,0 +1,202 @@
+package paths
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// pathFS exposes a path, and everything beneath it, as an fs.FS.
+type pathFS struct{ root *Path }
+
+var (
+	_ fs.ReadDirFS  = pathFS{}
+	_ fs.ReadFileFS = pathFS{}
+	_ fs.StatFS     = pathFS{}
+	_ fs.GlobFS     = pathFS{}
+	_ fs.SubFS      = pathFS{}
+)
+
+// FS returns an fs.FS rooted at p. Symlinks are followed, including those that lead outside p.
+func (p *Path) FS() fs.FS { return pathFS{p} }
+
+// SystemFS returns an fs.FS rooted at the root of sys.
+func SystemFS(sys System) fs.FS { return NewTreeWithSystem(sys).FS() }
+
+func (f pathFS) path(op, name string) (*Path, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return f.root.Join(filepath.FromSlash(name)), nil
+}
+
+// stat returns information about the given path, following symlinks.
+func (f pathFS) stat(op, name string) (p *Path, info fs.FileInfo, err error) {
+	if p, err = f.path(op, name); err != nil {
+		return
+	}
+	resolved, err := p.EvalSymlinks()
+	if err == nil {
+		info, err = resolved.Stat()
+	}
+	if err != nil {
+		return nil, nil, fsError(op, name, err)
+	}
+	return p, renamedInfo{info, path.Base(name)}, nil
+}
+
+func (f pathFS) Open(name string) (fs.File, error) {
+	p, info, err := f.stat("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &fsDir{fs: f, name: name, info: info}, nil
+	}
+	file, err := p.Open()
+	if err != nil {
+		return nil, fsError("open", name, err)
+	}
+	return &fsFile{file, info}, nil
+}
+
+func (f pathFS) Stat(name string) (fs.FileInfo, error) {
+	_, info, err := f.stat("stat", name)
+	return info, err
+}
+
+func (f pathFS) ReadFile(name string) ([]byte, error) {
+	p, info, err := f.stat("read", name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: ErrDirectory}
+	}
+	b, err := p.ReadBytes()
+	if err != nil {
+		return nil, fsError("read", name, err)
+	}
+	return b, nil
+}
+
+func (f pathFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	p, info, err := f.stat("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: ErrNonDirectory}
+	}
+	entries, err := p.tree.sys.ReadDir(p.path)
+	if err != nil {
+		return nil, fsError("readdir", name, err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+func (f pathFS) Glob(pattern string) (names []string, err error) {
+	if _, err = path.Match(pattern, ""); err != nil {
+		return
+	}
+	matches, err := f.root.Glob(filepath.FromSlash(pattern))
+	if err != nil {
+		return
+	}
+	for _, match := range matches {
+		if name := filepath.ToSlash(f.root.nameOf(match)); name != "" {
+			names = append(names, name)
+		}
+	}
+	return
+}
+
+func (f pathFS) Sub(dir string) (fs.FS, error) {
+	p, info, err := f.stat("sub", dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, &fs.PathError{Op: "sub", Path: dir, Err: ErrNonDirectory}
+	}
+	return pathFS{p}, nil
+}
+
+// fsError wraps err in an *fs.PathError. Errors from this package are replaced with their fs equivalents, where they
+// exist, so they can be tested with errors.Is.
+func fsError(op, name string, err error) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
+	switch err {
+	case ErrPathNotFound, ErrBrokenLink:
+		err = fs.ErrNotExist
+	case ErrFileExists:
+		err = fs.ErrExist
+	case ErrNotWritable:
+		err = fs.ErrPermission
+	case ErrInvalid:
+		err = fs.ErrInvalid
+	}
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+type fsFile struct {
+	File
+	info fs.FileInfo
+}
+
+func (f *fsFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+type fsDir struct {
+	fs      pathFS
+	name    string
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	read    bool
+}
+
+func (d *fsDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *fsDir) Close() error               { return nil }
+
+func (d *fsDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: ErrDirectory}
+}
+
+func (d *fsDir) ReadDir(n int) (entries []fs.DirEntry, err error) {
+	if !d.read {
+		if d.entries, err = d.fs.ReadDir(d.name); err != nil {
+			return
+		}
+		d.read = true
+	}
+	if n <= 0 || n >= len(d.entries) {
+		entries, d.entries = d.entries, nil
+		if n > 0 && len(entries) == 0 {
+			err = io.EOF
+		}
+		return
+	}
+	entries, d.entries = d.entries[:n], d.entries[n:]
+	return
+}
+
+// fsName converts absolute paths on a System to names in an fs.FS.
+func fsName(sys System, name string) string {
+	name = strings.TrimPrefix(name[len(filepath.VolumeName(name)):], string(filepath.Separator))
+	root := sys.Root()
+	root = strings.Trim(root[len(filepath.VolumeName(root)):], string(filepath.Separator))
+	if root != "" {
+		name = strings.TrimPrefix(strings.TrimPrefix(name, root), string(filepath.Separator))
+	}
+	if name = filepath.ToSlash(name); name == "" {
+		return "."
+	}
+	return name
+}
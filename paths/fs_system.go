@@ -0,0 +1,165 @@
+package paths
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+)
+
+// fsSystem is a read-only System backed by an fs.FS.
+type fsSystem struct {
+	fsys fs.FS
+	root string
+}
+
+// NewFSSystem returns a read-only System whose root is the root of fsys. Operations that would modify the system fail
+// with ErrNotWritable.
+func NewFSSystem(fsys fs.FS) System {
+	return &fsSystem{fsys, LocalSystem.Root()}
+}
+
+func (f *fsSystem) name(name string) string { return fsName(f, name) }
+
+func (f *fsSystem) Root() string               { return f.root }
+func (f *fsSystem) Getwd() (string, error)     { return f.root, nil }
+func (f *fsSystem) Join(elem ...string) string { return filepath.Join(elem...) }
+func (f *fsSystem) SupportsSymlinks() bool     { return false }
+
+func (f *fsSystem) Chmod(name string, _ os.FileMode) error {
+	return pathError("chmod", name, ErrNotWritable)
+}
+
+func (f *fsSystem) MkdirAll(path string, _ os.FileMode) error {
+	return pathError("mkdir", path, ErrNotWritable)
+}
+
+func (f *fsSystem) Remove(name string) error {
+	return pathError("remove", name, ErrNotWritable)
+}
+
+func (f *fsSystem) RemoveAll(path string) error {
+	return pathError("remove", path, ErrNotWritable)
+}
+
+func (f *fsSystem) Rename(oldpath, newpath string) error {
+	return linkError("rename", oldpath, newpath, ErrNotWritable)
+}
+
+func (f *fsSystem) Symlink(oldname, newname string) error {
+	return linkError("symlink", oldname, newname, ErrNotWritable)
+}
+
+func (f *fsSystem) Chtimes(name string, _, _ time.Time) error {
+	return pathError("chtimes", name, ErrNotWritable)
+}
+
+func (f *fsSystem) Chown(name string, _, _ int) error {
+	return pathError("chown", name, ErrNotWritable)
+}
+
+func (f *fsSystem) Lchown(name string, _, _ int) error {
+	return pathError("lchown", name, ErrNotWritable)
+}
+
+func (f *fsSystem) Link(oldname, newname string) error {
+	return linkError("link", oldname, newname, ErrNotWritable)
+}
+
+func (f *fsSystem) CurrentUser() (*user.User, error) {
+	return &user.User{
+		Uid:      "0",
+		Gid:      "0",
+		Username: "root",
+		Name:     "Root",
+		HomeDir:  f.root,
+	}, nil
+}
+
+func (f *fsSystem) Lstat(name string) (os.FileInfo, error) {
+	info, err := fs.Stat(f.fsys, f.name(name))
+	return info, f.error("lstat", name, err)
+}
+
+// Stat is the same as Lstat, since an fs.FS has no notion of symlinks.
+func (f *fsSystem) Stat(name string) (os.FileInfo, error) { return f.Lstat(name) }
+
+func (f *fsSystem) ReadDir(name string) ([]os.DirEntry, error) {
+	entries, err := fs.ReadDir(f.fsys, f.name(name))
+	return entries, f.error("readdir", name, err)
+}
+
+func (f *fsSystem) ReadFile(name string) ([]byte, error) {
+	b, err := fs.ReadFile(f.fsys, f.name(name))
+	return b, f.error("open", name, err)
+}
+
+func (f *fsSystem) Readlink(name string) (string, error) {
+	if _, err := f.Lstat(name); err != nil {
+		return "", err
+	}
+	return "", pathError("readlink", name, ErrNonLink)
+}
+
+func (f *fsSystem) Glob(pattern string) (matches []string, err error) {
+	names, err := fs.Glob(f.fsys, f.name(pattern))
+	if err != nil {
+		return
+	}
+	matches = make([]string, len(names))
+	for i, name := range names {
+		matches[i] = filepath.Join(f.root, filepath.FromSlash(name))
+	}
+	return
+}
+
+func (f *fsSystem) OpenFile(name string, flag int, _ os.FileMode) (File, error) {
+	if isWriteFlag(flag) {
+		return nil, pathError("open", name, ErrNotWritable)
+	}
+	file, err := f.fsys.Open(f.name(name))
+	if err != nil {
+		return nil, f.error("open", name, err)
+	}
+	if info, err := file.Stat(); err != nil {
+		_ = file.Close()
+		return nil, f.error("open", name, err)
+	} else if info.IsDir() {
+		_ = file.Close()
+		return nil, pathError("open", name, ErrDirectory)
+	}
+	return fsSystemFile{file}, nil
+}
+
+// error replaces fs errors with their equivalents from this package, wrapped in an *fs.PathError that has the system
+// path rather than the fs name, so that callers see the same errors they would from a VirtualSystem.
+func (f *fsSystem) error(op, name string, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, fs.ErrNotExist):
+		err = ErrPathNotFound
+	case errors.Is(err, fs.ErrInvalid):
+		err = ErrInvalid
+	default:
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			err = pathErr.Err
+		}
+	}
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+type fsSystemFile struct{ fs.File }
+
+func (f fsSystemFile) Write([]byte) (int, error) { return 0, ErrNotWritable }
+
+func (f fsSystemFile) Seek(offset int64, whence int) (int64, error) {
+	if seeker, ok := f.File.(io.Seeker); ok {
+		return seeker.Seek(offset, whence)
+	}
+	return 0, ErrInvalid
+}
@@ -0,0 +1,50 @@
+package paths_test
+
+import (
+	"errors"
+	. "github.com/hx/golib/paths"
+	. "github.com/hx/golib/testing"
+	"io/fs"
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+func TestPath_FS(t *testing.T) {
+	eachSystem(t, func(t *testing.T, tree *Tree, dir *Path) {
+		dir.Join("a", "one").MustWriteString("1")
+		dir.Join("a", "b", "two").MustWriteString("22")
+		dir.Join("three").MustWriteString("333")
+		dir.Join("empty").MustMake()
+		if LocalSystem.SupportsSymlinks() {
+			dir.Join("a").MustSymlinkTo(dir.Join("link"))
+		}
+		fsys := dir.FS()
+		Ok(t, fstest.TestFS(fsys, "a/one", "a/b/two", "three", "empty"))
+
+		_, err := fs.Stat(fsys, "missing")
+		Assert(t, errors.Is(err, fs.ErrNotExist), "expected fs.ErrNotExist, got %v", err)
+
+		matches, err := fs.Glob(fsys, "a/*")
+		Ok(t, err)
+		Equals(t, []string{"a/b", "a/one"}, matches)
+	})
+}
+
+func TestNewFSSystem(t *testing.T) {
+	tree := NewTreeWithSystem(NewFSSystem(fstest.MapFS{
+		"a/one":   {Data: []byte("1")},
+		"a/b/two": {Data: []byte("22"), Mode: 0600},
+	}))
+	Equals(t, "1", tree.Join("a", "one").MustReadString())
+	Equals(t, os.FileMode(0600), tree.Join("a", "b", "two").MustStat().Mode())
+	Equals(t, 2, len(tree.Join("a").MustChildren()))
+	Equals(t, 1, len(tree.MustGlob("a/o*")))
+	Equals(t, false, tree.Join("missing").Exists())
+	err := tree.Join("a", "one").WriteString("x")
+	Assert(t, errors.Is(err, ErrNotWritable), "expected ErrNotWritable, got %v", err)
+	err = tree.Join("a", "one").Delete()
+	Assert(t, errors.Is(err, ErrNotWritable), "expected ErrNotWritable, got %v", err)
+
+	Ok(t, fstest.TestFS(tree.FS(), "a/one", "a/b/two"))
+}
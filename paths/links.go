@@ -0,0 +1,105 @@
+package paths
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// maxLinkHops is the number of symlinks that will be followed while resolving a single path before giving up with
+// ErrLinkLoop. Linux uses 40; we're more lenient.
+const maxLinkHops = 255
+
+// segments splits a path into its non-empty components, excluding any volume name.
+func segments(path string) (parts []string) {
+	path = path[len(filepath.VolumeName(path)):]
+	for _, part := range strings.Split(path, string(os.PathSeparator)) {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return
+}
+
+func isAbs(path string) bool {
+	return filepath.IsAbs(path) || strings.HasPrefix(path, string(os.PathSeparator))
+}
+
+// EvalSymlinks returns p with every symlink in every one of its components replaced with its target. Components are
+// resolved one at a time, so ".." in a link target refers to the parent of the link's target, and not the parent of the
+// link.
+//
+// It works through any System. Every component must exist; if a link's target doesn't, ErrBrokenLink is returned, and
+// if more than 255 links are followed, ErrLinkLoop is returned.
+func (p *Path) EvalSymlinks() (*Path, error) {
+	var (
+		root     = p.tree.Path
+		resolved = root
+		pending  = segments(p.path)
+		own      = len(pending) // number of pending parts that came from p itself, rather than from link targets
+		hops     int
+	)
+	for len(pending) > 0 {
+		part := pending[0]
+		fromLink := len(pending) > own
+		if !fromLink {
+			own--
+		}
+		pending = pending[1:]
+		switch part {
+		case ".":
+			continue
+		case "..":
+			resolved = resolved.Parent()
+			continue
+		}
+		next := resolved.Join(part)
+		stat, err := next.Stat()
+		if err != nil {
+			if fromLink {
+				return nil, pathError("evalsymlinks", p.path, ErrBrokenLink)
+			}
+			return nil, err
+		}
+		if stat.Mode()&os.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+		if hops++; hops > maxLinkHops {
+			return nil, pathError("evalsymlinks", p.path, ErrLinkLoop)
+		}
+		target, err := p.tree.sys.Readlink(next.path)
+		if err != nil {
+			return nil, err
+		}
+		if isAbs(target) {
+			resolved = root
+		}
+		pending = append(segments(target), pending...)
+	}
+	return resolved, nil
+}
+func (p *Path) MustEvalSymlinks() *Path { return must1(p.EvalSymlinks()).(*Path) }
+
+// Realpath is the same as EvalSymlinks. Since a Path is always absolute, the result is its canonical path.
+func (p *Path) Realpath() (*Path, error) { return p.EvalSymlinks() }
+func (p *Path) MustRealpath() *Path      { return must1(p.Realpath()).(*Path) }
+
+// IsSymlink reports whether p is a symlink, whether or not its target exists.
+func (p *Path) IsSymlink() bool {
+	info, err := p.Stat()
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
+
+// IsBrokenLink reports whether p is a symlink whose target can't be reached, either because it doesn't exist, or
+// because it leads to a loop of symlinks.
+func (p *Path) IsBrokenLink() bool {
+	if !p.IsSymlink() {
+		return false
+	}
+	_, err := p.StatFollowingLinks()
+	return errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ELOOP)
+}
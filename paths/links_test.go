@@ -0,0 +1,51 @@
+package paths_test
+
+import (
+	"errors"
+	. "github.com/hx/golib/paths"
+	. "github.com/hx/golib/testing"
+	"io/fs"
+	"testing"
+)
+
+func TestPath_EvalSymlinks(t *testing.T) {
+	eachSystem(t, func(t *testing.T, _ *Tree, dir *Path) {
+		actual := dir.MustEvalSymlinks().Join("real")
+		actual.Join("file").MustWriteString("hello")
+		first, second := dir.Join("first"), dir.Join("second")
+		actual.MustSymlinkTo(first)
+		first.MustSymlinkTo(second)
+
+		Equals(t, actual.Join("file").String(), second.Join("file").MustEvalSymlinks().String())
+		Equals(t, actual.String(), first.MustRealpath().String())
+		Equals(t, actual.String(), actual.MustEvalSymlinks().String())
+
+		_, err := dir.Join("missing").EvalSymlinks()
+		Assert(t, errors.Is(err, fs.ErrNotExist), "expected fs.ErrNotExist, got %v", err)
+
+		broken := dir.Join("broken")
+		dir.Join("missing").MustSymlinkTo(broken)
+		_, err = broken.EvalSymlinks()
+		Assert(t, errors.Is(err, ErrBrokenLink), "expected ErrBrokenLink, got %v", err)
+
+		loopA, loopB := dir.Join("a"), dir.Join("b")
+		loopA.MustSymlinkTo(loopB)
+		loopB.MustSymlinkTo(loopA)
+		_, err = loopA.EvalSymlinks()
+		Assert(t, errors.Is(err, ErrLinkLoop), "expected ErrLinkLoop, got %v", err)
+	})
+}
+
+func TestPath_IsBrokenLink(t *testing.T) {
+	eachSystem(t, func(t *testing.T, _ *Tree, dir *Path) {
+		file, link, broken, loop := dir.Join("file"), dir.Join("link"), dir.Join("broken"), dir.Join("loop")
+		file.MustWriteString("hello")
+		file.MustSymlinkTo(link)
+		dir.Join("missing").MustSymlinkTo(broken)
+		loop.MustSymlinkTo(loop)
+
+		Equals(t, []bool{false, true, true, true}, []bool{file.IsSymlink(), link.IsSymlink(), broken.IsSymlink(), loop.IsSymlink()})
+		Equals(t, []bool{false, false, true, true}, []bool{file.IsBrokenLink(), link.IsBrokenLink(), broken.IsBrokenLink(), loop.IsBrokenLink()})
+		Equals(t, false, dir.Join("missing").IsBrokenLink())
+	})
+}
@@ -3,7 +3,17 @@
 
 package paths
 
+import "syscall"
+
 const root = "/"
 
 func (l local) Root() string           { return root }
 func (l local) SupportsSymlinks() bool { return true }
+func (l local) FoldsCase() bool        { return false }
+
+func sysOwner(sys interface{}) (uid, gid int, ok bool) {
+	if stat, isStat := sys.(*syscall.Stat_t); isStat {
+		return int(stat.Uid), int(stat.Gid), true
+	}
+	return
+}
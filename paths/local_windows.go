@@ -12,3 +12,6 @@ var root = strings.ToUpper(os.Getenv("SYSTEMDRIVE")) + "\\"
 
 func (l local) Root() string           { return root }
 func (l local) SupportsSymlinks() bool { return false }
+func (l local) FoldsCase() bool        { return true }
+
+func sysOwner(interface{}) (uid, gid int, ok bool) { return }
@@ -0,0 +1,114 @@
+package paths
+
+import (
+	"os"
+	"sync"
+)
+
+// FileLock is an advisory lock held on a path.
+type FileLock interface {
+	// Unlock releases the lock. Calling it more than once has no effect.
+	Unlock() error
+}
+
+// lockingSystem is implemented by systems that can lock their own files, such as LocalSystem, which uses flock where
+// it's available, and VirtualSystem.
+type lockingSystem interface {
+	lock(name string, exclusive, wait bool) (FileLock, error)
+}
+
+// processLocks holds the locks of systems that can't lock their own files. Those locks are only visible within the
+// current process, and paths are compared by name alone, so the same name on different systems shares a lock.
+var processLocks inProcessLocks
+
+// Lock acquires an exclusive advisory lock on p, waiting until no other lock is held on it. If p doesn't exist, it's
+// created as an empty file.
+//
+// Locks are advisory, so they only exclude other callers of Lock, RLock, TryLock and TryRLock. Locks on LocalSystem
+// use flock on Linux and macOS, so they exclude other processes too. On other systems, and on systems that wrap
+// LocalSystem, locks only exclude callers in the same process.
+func (p *Path) Lock() (FileLock, error) { return p.lock(true, true) }
+
+// RLock acquires a shared advisory lock on p, waiting until no exclusive lock is held on it.
+func (p *Path) RLock() (FileLock, error) { return p.lock(false, true) }
+
+// TryLock acquires an exclusive advisory lock on p if it can do so immediately, and fails with ErrLocked otherwise.
+func (p *Path) TryLock() (FileLock, error) { return p.lock(true, false) }
+
+// TryRLock acquires a shared advisory lock on p if it can do so immediately, and fails with ErrLocked otherwise.
+func (p *Path) TryRLock() (FileLock, error) { return p.lock(false, false) }
+
+func (p *Path) MustLock() FileLock     { return must1(p.Lock()).(FileLock) }
+func (p *Path) MustRLock() FileLock    { return must1(p.RLock()).(FileLock) }
+func (p *Path) MustTryLock() FileLock  { return must1(p.TryLock()).(FileLock) }
+func (p *Path) MustTryRLock() FileLock { return must1(p.TryRLock()).(FileLock) }
+
+func (p *Path) lock(exclusive, wait bool) (FileLock, error) {
+	sys := p.tree.sys
+	if _, err := sys.Lstat(p.path); err != nil {
+		file, err := sys.OpenFile(p.path, os.O_CREATE|os.O_RDWR, 0644)
+		if err != nil {
+			return nil, err
+		}
+		if err = file.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if locker, ok := sys.(lockingSystem); ok {
+		return locker.lock(p.path, exclusive, wait)
+	}
+	return processLocks.lock(p.path, exclusive, wait)
+}
+
+// inProcessLocks is a set of named reader/writer locks.
+type inProcessLocks struct {
+	mutex sync.Mutex
+	cond  *sync.Cond
+
+	// The number of shared locks held on each name, or -1 if an exclusive lock is held.
+	held map[string]int
+}
+
+func (l *inProcessLocks) lock(name string, exclusive, wait bool) (FileLock, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.cond == nil {
+		l.cond = sync.NewCond(&l.mutex)
+		l.held = make(map[string]int)
+	}
+	for l.held[name] < 0 || exclusive && l.held[name] > 0 {
+		if !wait {
+			return nil, ErrLocked
+		}
+		l.cond.Wait()
+	}
+	if exclusive {
+		l.held[name] = -1
+	} else {
+		l.held[name]++
+	}
+	return &inProcessLock{locks: l, name: name}, nil
+}
+
+type inProcessLock struct {
+	locks *inProcessLocks
+	name  string
+	once  sync.Once
+}
+
+func (l *inProcessLock) Unlock() error {
+	l.once.Do(func() {
+		locks := l.locks
+		locks.mutex.Lock()
+		defer locks.mutex.Unlock()
+		if locks.held[l.name]--; locks.held[l.name] <= 0 {
+			delete(locks.held, l.name)
+		}
+		locks.cond.Broadcast()
+	})
+	return nil
+}
+
+func (v *VirtualSystem) lock(name string, exclusive, wait bool) (FileLock, error) {
+	return v.locks.lock(name, exclusive, wait)
+}
@@ -0,0 +1,152 @@
+package paths
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lockFilePollInterval is how often LockFile checks whether a lock file has been released.
+const lockFilePollInterval = 100 * time.Millisecond
+
+// LockFile acquires a lock by creating p exclusively, waiting until it can do so. The file records the ID of the
+// current process and the name of its host, and is removed when the lock is released.
+//
+// Unlike Lock, this works across hosts that share a directory, e.g. over a network file system, as long as the file
+// system supports exclusive creation. It also works on any System, since it needs nothing more than OpenFile.
+//
+// An existing lock file is considered stale, and removed, if it's older than maxAge, or if it was created on this host
+// by a process that is no longer running. A maxAge of zero means lock files never become stale with age alone.
+func (p *Path) LockFile(maxAge time.Duration) (FileLock, error) {
+	for {
+		lock, err := p.TryLockFile(maxAge)
+		if err != ErrLocked {
+			return lock, err
+		}
+		time.Sleep(lockFilePollInterval)
+	}
+}
+
+// TryLockFile is like LockFile, but fails with ErrLocked if the lock can't be acquired immediately.
+func (p *Path) TryLockFile(maxAge time.Duration) (FileLock, error) {
+	owner := lockFileOwner()
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := p.tree.sys.OpenFile(p.path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			if _, err = file.Write([]byte(owner)); err == nil {
+				err = file.Close()
+			} else {
+				_ = file.Close()
+			}
+			if err != nil {
+				_ = p.tree.sys.Remove(p.path)
+				return nil, err
+			}
+			return &lockFile{path: p, owner: owner}, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		if !p.Exists() {
+			// The lock was released after we tried to create it.
+			continue
+		}
+		if !p.isStaleLockFile(maxAge) {
+			break
+		}
+		if err = p.removeStaleLockFile(maxAge); err != nil {
+			return nil, err
+		}
+	}
+	return nil, ErrLocked
+}
+
+func (p *Path) MustLockFile(maxAge time.Duration) FileLock {
+	return must1(p.LockFile(maxAge)).(FileLock)
+}
+func (p *Path) MustTryLockFile(maxAge time.Duration) FileLock {
+	return must1(p.TryLockFile(maxAge)).(FileLock)
+}
+
+func (p *Path) isStaleLockFile(maxAge time.Duration) bool {
+	info, err := p.Stat()
+	if err != nil {
+		return false
+	}
+	if maxAge > 0 && time.Since(info.ModTime()) > maxAge {
+		return true
+	}
+	contents, err := p.tree.sys.ReadFile(p.path)
+	if err != nil {
+		return false
+	}
+	fields := strings.Fields(string(contents))
+	if len(fields) != 2 {
+		return false
+	}
+	pid, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return false
+	}
+	host, _ := os.Hostname()
+	return fields[1] == host && !processExists(pid)
+}
+
+// removeStaleLockFile removes p, which isStaleLockFile has found to be stale. Another process may have replaced it with
+// a fresh lock file since then, so rather than removing p directly, it's moved aside first, which only one process can
+// do, and checked again before it's removed. If the file that was moved aside turns out to be fresh, it's put back.
+//
+// The file is put back with Link, so that it can't replace yet another lock file created in the meantime. If that
+// happens, the fresh lock file that was moved aside is lost, and two processes may briefly both believe they hold the
+// lock. Systems that don't support Link fall back to Rename, which replaces any such lock file instead.
+func (p *Path) removeStaleLockFile(maxAge time.Duration) error {
+	sys := p.tree.sys
+	aside, err := p.Parent().tempChild("." + p.Base() + ".*.stale")
+	if err != nil {
+		return err
+	}
+	if err = sys.Rename(p.path, aside.path); err != nil {
+		if p.Exists() {
+			return err
+		}
+		// Another process got there first.
+		return nil
+	}
+	if aside.isStaleLockFile(maxAge) {
+		return sys.Remove(aside.path)
+	}
+	if err = sys.Link(aside.path, p.path); err == nil || p.Exists() {
+		return sys.Remove(aside.path)
+	}
+	return sys.Rename(aside.path, p.path)
+}
+
+// lockFileOwner returns the contents of lock files created by the current process.
+func lockFileOwner() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%d %s\n", os.Getpid(), host)
+}
+
+type lockFile struct {
+	path  *Path
+	owner string
+	once  sync.Once
+}
+
+// Unlock removes the lock file, unless it has been replaced by another owner since the lock was acquired.
+func (l *lockFile) Unlock() (err error) {
+	l.once.Do(func() {
+		sys := l.path.tree.sys
+		contents, readErr := sys.ReadFile(l.path.path)
+		if readErr != nil || string(contents) != l.owner {
+			return
+		}
+		err = sys.Remove(l.path.path)
+	})
+	return
+}
@@ -0,0 +1,55 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package paths
+
+import (
+	"os"
+	"sync"
+	"syscall"
+)
+
+func (l local) lock(name string, exclusive, wait bool) (FileLock, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	if !wait {
+		how |= syscall.LOCK_NB
+	}
+	for {
+		err = syscall.Flock(int(file.Fd()), how)
+		if err != syscall.EINTR {
+			break
+		}
+	}
+	if err != nil {
+		_ = file.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, ErrLocked
+		}
+		return nil, &os.PathError{Op: "flock", Path: name, Err: err}
+	}
+	return &flockLock{file: file}, nil
+}
+
+type flockLock struct {
+	file *os.File
+	once sync.Once
+}
+
+// Unlock releases the lock by closing the file it was taken on.
+func (l *flockLock) Unlock() (err error) {
+	l.once.Do(func() { err = l.file.Close() })
+	return
+}
+
+// processExists reports whether a process with the given ID is running on this host.
+func processExists(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,17 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package paths
+
+import "os"
+
+// processExists reports whether a process with the given ID is running on this host. Where that can't be determined,
+// processes are assumed to be running.
+func processExists(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = process.Release()
+	return true
+}
@@ -0,0 +1,86 @@
+package paths_test
+
+import (
+	"errors"
+	"fmt"
+	. "github.com/hx/golib/paths"
+	. "github.com/hx/golib/testing"
+	"io/fs"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPath_Lock(t *testing.T) {
+	eachSystem(t, func(t *testing.T, _ *Tree, dir *Path) {
+		path := dir.Join("locked")
+		lock := path.MustLock()
+		Assert(t, path.Exists(), "locking should create the file")
+
+		_, err := path.TryLock()
+		Equals(t, ErrLocked, err)
+		_, err = path.TryRLock()
+		Equals(t, ErrLocked, err)
+
+		acquired := make(chan FileLock)
+		go func() { acquired <- path.MustLock() }()
+		select {
+		case <-acquired:
+			t.Fatal("lock should not be acquired while held")
+		case <-time.After(20 * time.Millisecond):
+		}
+		Ok(t, lock.Unlock())
+		Ok(t, lock.Unlock())
+		Ok(t, (<-acquired).Unlock())
+
+		first := path.MustRLock()
+		second := path.MustTryRLock()
+		_, err = path.TryLock()
+		Equals(t, ErrLocked, err)
+		Ok(t, first.Unlock())
+		Ok(t, second.Unlock())
+		Ok(t, path.MustTryLock().Unlock())
+	})
+}
+
+func TestPath_LockFile(t *testing.T) {
+	eachSystem(t, func(t *testing.T, _ *Tree, dir *Path) {
+		host, _ := os.Hostname()
+		path := dir.Join("build.lock")
+		lock := path.MustTryLockFile(0)
+		Equals(t, fmt.Sprintf("%d %s\n", os.Getpid(), host), path.MustReadString())
+		_, err := path.TryLockFile(0)
+		Equals(t, ErrLocked, err)
+		Ok(t, lock.Unlock())
+		Equals(t, false, path.Exists())
+
+		path.MustWriteString(fmt.Sprintf("999999999 %s\n", host))
+		lock = path.MustTryLockFile(0)
+		Equals(t, Paths{path}, dir.MustChildren())
+		Ok(t, lock.Unlock())
+
+		path.MustWriteString("1 some-other-host\n")
+		_, err = path.TryLockFile(0)
+		Equals(t, ErrLocked, err)
+		time.Sleep(time.Millisecond)
+		Ok(t, path.MustTryLockFile(time.Nanosecond).Unlock())
+		Equals(t, false, path.Exists())
+	})
+}
+
+func TestPath_TryLockFile_Released(t *testing.T) {
+	sys := NewFaultySystem(NewVirtualSystem())
+	tree := NewTreeWithSystem(sys)
+	path := tree.Join("build.lock")
+
+	// The lock is released between the failed create and the check for an existing lock file.
+	sys.AddFault(&Fault{Method: "OpenFile", Path: path.String(), Nth: 1, Err: fs.ErrExist})
+	lock, err := path.TryLockFile(0)
+	Ok(t, err)
+	Ok(t, lock.Unlock())
+
+	failure := errors.New("failure")
+	sys.AddFault(&Fault{Method: "OpenFile", Path: path.String(), Err: failure})
+	_, err = path.TryLockFile(0)
+	Assert(t, errors.Is(err, failure), "expected failure, got %v", err)
+}
@@ -0,0 +1,111 @@
+package paths
+
+import (
+	"os"
+	"time"
+)
+
+// FileID identifies a file, regardless of the path used to reach it. Two paths with the same FileID are hard links to
+// the same file, or one of them is a symlink that leads to the other.
+type FileID struct {
+	Device uint64
+	Inode  uint64
+}
+
+// fileMetadata holds information that os.FileInfo doesn't expose directly.
+type fileMetadata struct {
+	accessed time.Time
+	links    uint64
+	id       FileID
+}
+
+// hasMetadata is implemented by the FileInfo values returned by VirtualSystem.
+type hasMetadata interface{ fileMetadata() fileMetadata }
+
+func infoMetadata(info os.FileInfo) (fileMetadata, bool) {
+	info = unwrapInfo(info)
+	if m, ok := info.(hasMetadata); ok {
+		return m.fileMetadata(), true
+	}
+	return sysMetadata(info.Sys())
+}
+
+// renamedInfo overrides the name of a FileInfo, e.g. so that the information about the target of a symlink can be
+// reported under the name of the link.
+type renamedInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (i renamedInfo) Name() string { return i.name }
+
+func unwrapInfo(info os.FileInfo) os.FileInfo {
+	for {
+		renamed, ok := info.(renamedInfo)
+		if !ok {
+			return info
+		}
+		info = renamed.FileInfo
+	}
+}
+
+// StatFollowingLinks returns information about p, or, if p is a symlink, about its ultimate target.
+func (p *Path) StatFollowingLinks() (os.FileInfo, error) { return p.tree.sys.Stat(p.path) }
+func (p *Path) MustStatFollowingLinks() os.FileInfo {
+	return must1(p.StatFollowingLinks()).(os.FileInfo)
+}
+
+func (p *Path) metadata(follow bool) (m fileMetadata, err error) {
+	var info os.FileInfo
+	if follow {
+		info, err = p.StatFollowingLinks()
+	} else {
+		info, err = p.Stat()
+	}
+	if err != nil {
+		return
+	}
+	m, ok := infoMetadata(info)
+	if !ok {
+		err = ErrUnsupported
+	}
+	return
+}
+
+// AccessTime returns the time p was last accessed, without following symlinks. If p's System doesn't report access
+// times, ErrUnsupported is returned.
+func (p *Path) AccessTime() (time.Time, error) {
+	m, err := p.metadata(false)
+	return m.accessed, err
+}
+func (p *Path) MustAccessTime() time.Time { return must1(p.AccessTime()).(time.Time) }
+
+// LinkCount returns the number of hard links to p, without following symlinks. If p's System doesn't report link
+// counts, ErrUnsupported is returned.
+func (p *Path) LinkCount() (uint64, error) {
+	m, err := p.metadata(false)
+	return m.links, err
+}
+func (p *Path) MustLinkCount() uint64 { return must1(p.LinkCount()).(uint64) }
+
+// FileID returns the identity of the file at p, following symlinks. If p's System doesn't report file identities,
+// ErrUnsupported is returned.
+func (p *Path) FileID() (FileID, error) {
+	m, err := p.metadata(true)
+	return m.id, err
+}
+func (p *Path) MustFileID() FileID { return must1(p.FileID()).(FileID) }
+
+// IsSameFile reports whether p and other lead to the same file, following symlinks.
+func (p *Path) IsSameFile(other *Path) (bool, error) {
+	id, err := p.FileID()
+	if err != nil {
+		return false, err
+	}
+	otherID, err := other.FileID()
+	if err != nil {
+		return false, err
+	}
+	return id == otherID, nil
+}
+func (p *Path) MustIsSameFile(other *Path) bool { return must1(p.IsSameFile(other)).(bool) }
@@ -0,0 +1,60 @@
+package paths_test
+
+import (
+	. "github.com/hx/golib/paths"
+	. "github.com/hx/golib/testing"
+	"testing"
+	"time"
+)
+
+func TestPath_StatFollowingLinks(t *testing.T) {
+	eachSystem(t, func(t *testing.T, _ *Tree, dir *Path) {
+		sub := dir.Join("sub").MustMake()
+		sub.Join("file").MustWriteString("hello")
+		link := dir.Join("link")
+		sub.MustSymlinkTo(link)
+
+		info := link.MustStatFollowingLinks()
+		Equals(t, "link", info.Name())
+		Assert(t, info.IsDir(), "stat should follow the link")
+		Assert(t, link.IsDir(), "a link to a directory is a directory")
+		Assert(t, !link.IsNonDir(), "a link to a directory is not a non-directory")
+		Equals(t, 1, len(link.MustChildren()))
+
+		broken := dir.Join("broken")
+		dir.Join("missing").MustSymlinkTo(broken)
+		_, err := broken.StatFollowingLinks()
+		Assert(t, err != nil, "stat of a broken link should fail")
+		Assert(t, broken.IsNonDir(), "a broken link is a non-directory")
+	})
+}
+
+func TestPath_Metadata(t *testing.T) {
+	eachSystem(t, func(t *testing.T, _ *Tree, dir *Path) {
+		file := dir.Join("file")
+		file.MustWriteString("one")
+		hard := dir.Join("hard")
+		file.MustLinkTo(hard)
+		other := dir.Join("other")
+		other.MustWriteString("one")
+
+		Equals(t, uint64(2), file.MustLinkCount())
+		Assert(t, file.MustIsSameFile(hard), "hard links should be the same file")
+		Assert(t, !file.MustIsSameFile(other), "different files should not be the same file")
+
+		soft := dir.Join("soft")
+		file.MustSymlinkTo(soft)
+		Assert(t, soft.MustIsSameFile(file), "a symlink should be the same file as its target")
+
+		moved := dir.Join("moved")
+		hard.MustRename(moved)
+		Equals(t, uint64(2), file.MustLinkCount())
+		other.MustRename(moved)
+		Equals(t, uint64(1), file.MustLinkCount())
+		Equals(t, uint64(1), moved.MustLinkCount())
+
+		before := time.Now().Add(-time.Second)
+		file.MustTouch()
+		Assert(t, file.MustAccessTime().After(before), "access time should be updated by Touch")
+	})
+}
@@ -8,6 +8,7 @@ type RenameEvent struct{ TargetEvent }
 type SymlinkEvent struct{ TargetEvent }
 type CopyEvent struct{ TargetEvent }
 type CopyOverEvent struct{ TargetEvent }
+type LinkEvent struct{ TargetEvent }
 
 func (p *Path) Rename(target *Path) (err error) {
 	err = p.tree.sys.Rename(p.path, target.path)
@@ -23,11 +24,7 @@ func (p *Path) SymlinkTo(target *Path) error {
 	if !sys.SupportsSymlinks() {
 		return p.CopyTo(target)
 	}
-	if target.IsNonDir() {
-		stat, err := target.Stat()
-		if err != nil {
-			return err
-		}
+	if stat, err := target.Stat(); err == nil && !stat.IsDir() {
 		if stat.Mode()&os.ModeSymlink != 0 {
 			oldTarget, err := target.ReadLink()
 			if err != nil {
@@ -49,7 +46,19 @@ func (p *Path) SymlinkTo(target *Path) error {
 }
 func (p *Path) MustSymlinkTo(target *Path) { must(p.SymlinkTo(target)) }
 
-func (p *Path) CopyTo(target *Path) error {
+// LinkTo creates a hard link to p at target.
+func (p *Path) LinkTo(target *Path) error {
+	if err := p.tree.sys.Link(p.path, target.path); err != nil {
+		return err
+	}
+	p.tree.dispatch(LinkEvent{newTargetEvent(p, target)})
+	return nil
+}
+func (p *Path) MustLinkTo(target *Path) { must(p.LinkTo(target)) }
+
+func (p *Path) CopyTo(target *Path) error { return p.copyTo(target, 0644) }
+
+func (p *Path) copyTo(target *Path, mode os.FileMode) error {
 	existed := target.Exists()
 	if existed {
 		if equal, err := p.BytesAreEqual(target); err != nil {
@@ -58,7 +67,7 @@ func (p *Path) CopyTo(target *Path) error {
 			return nil
 		}
 	}
-	writer, err := p.tree.sys.OpenFile(target.path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	writer, err := target.tree.sys.OpenFile(target.path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, mode)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,571 @@
+package paths
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OverlaySystem stacks a writable upper System over a lower System, which it never modifies. Reads see the upper layer
+// where it has an entry, and fall through to the lower layer otherwise. Entries are copied from the lower layer to the
+// upper layer before they're changed, and deletions of entries in the lower layer are recorded as whiteouts, which hide
+// those entries and everything beneath them.
+//
+// Both layers share a single namespace of absolute paths, so a VirtualSystem makes a suitable upper layer for any lower
+// layer.
+type OverlaySystem struct {
+	upper     System
+	lower     System
+	whiteouts map[string]bool
+	mutex     sync.RWMutex
+}
+
+func NewOverlaySystem(upper, lower System) *OverlaySystem {
+	return &OverlaySystem{
+		upper:     upper,
+		lower:     lower,
+		whiteouts: make(map[string]bool),
+	}
+}
+
+func (o *OverlaySystem) Upper() System { return o.upper }
+func (o *OverlaySystem) Lower() System { return o.lower }
+
+// Whiteouts returns the sorted paths of entries in the lower layer that have been deleted from the overlay.
+func (o *OverlaySystem) Whiteouts() (paths []string) {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	for path := range o.whiteouts {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return
+}
+
+// Commit applies the changes held by the overlay to its lower layer: whited-out entries are removed, and then every
+// entry in the upper layer is written over the lower layer. The overlay's view of its contents is unchanged. Ownership
+// and hard links are not committed.
+func (o *OverlaySystem) Commit() error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	for path := range o.whiteouts {
+		if err := o.lower.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+	var dirs []string
+	var modes []os.FileMode
+	upper := NewTreeWithSystem(o.upper)
+	err := upper.Walk(func(path *Path, info os.FileInfo, err error) error {
+		if err != nil || path.path == upper.path {
+			return err
+		}
+		existing, lowerErr := o.lower.Lstat(path.path)
+		switch {
+		case info.IsDir():
+			if lowerErr == nil && !existing.IsDir() {
+				if err = o.lower.Remove(path.path); err != nil {
+					return err
+				}
+				lowerErr = ErrPathNotFound
+			}
+			if lowerErr != nil {
+				if err = o.lower.MkdirAll(path.path, 0755); err != nil {
+					return err
+				}
+			}
+			if lowerErr != nil || existing.Mode().Perm() != info.Mode().Perm() {
+				dirs, modes = append(dirs, path.path), append(modes, info.Mode().Perm())
+			}
+			return nil
+		case lowerErr == nil && (existing.IsDir() || info.Mode()&os.ModeSymlink != 0 || existing.Mode()&os.ModeSymlink != 0):
+			if err = o.lower.RemoveAll(path.path); err != nil {
+				return err
+			}
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := o.upper.Readlink(path.path)
+			if err == nil {
+				err = o.lower.Symlink(target, path.path)
+			}
+			return err
+		}
+		if err = copySystemFile(o.upper, o.lower, path.path, info.Mode().Perm()); err != nil {
+			return err
+		}
+		if err = o.lower.Chmod(path.path, info.Mode().Perm()); err != nil {
+			return err
+		}
+		return o.lower.Chtimes(path.path, info.ModTime(), info.ModTime())
+	})
+	for i := len(dirs) - 1; i >= 0 && err == nil; i-- {
+		err = o.lower.Chmod(dirs[i], modes[i])
+	}
+	return err
+}
+
+func (o *OverlaySystem) Root() string                     { return o.lower.Root() }
+func (o *OverlaySystem) Join(elem ...string) string       { return o.lower.Join(elem...) }
+func (o *OverlaySystem) Getwd() (dir string, err error)   { return o.lower.Getwd() }
+func (o *OverlaySystem) CurrentUser() (*user.User, error) { return o.lower.CurrentUser() }
+func (o *OverlaySystem) SupportsSymlinks() bool           { return o.upper.SupportsSymlinks() }
+func (o *OverlaySystem) FoldsCase() bool                  { return foldsCase(o.lower) }
+
+func (o *OverlaySystem) Lstat(name string) (os.FileInfo, error) {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	return o.lstat(name)
+}
+
+func (o *OverlaySystem) lstat(name string) (os.FileInfo, error) {
+	if info, err := o.upper.Lstat(name); err == nil {
+		return info, nil
+	}
+	return o.lstatLower(name)
+}
+
+func (o *OverlaySystem) Stat(name string) (os.FileInfo, error) {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	return o.stat(name, 0)
+}
+
+// stat returns information about name, following symlinks. Each symlink is resolved by the overlay, so its target may
+// be in either layer.
+func (o *OverlaySystem) stat(name string, hops int) (os.FileInfo, error) {
+	info, err := o.lstat(name)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return info, err
+	}
+	if hops >= maxLinkHops {
+		return nil, pathError("stat", name, ErrLinkLoop)
+	}
+	sys, err := o.layerOf(name)
+	if err != nil {
+		return nil, err
+	}
+	target, err := sys.Readlink(name)
+	if err != nil {
+		return nil, err
+	}
+	if !isAbs(target) {
+		target = filepath.Join(filepath.Dir(name), target)
+	}
+	targetInfo, err := o.stat(target, hops+1)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, pathError("stat", name, ErrBrokenLink)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return renamedInfo{targetInfo, info.Name()}, nil
+}
+
+// lstatLower returns information about name in the lower layer, unless it has been whited out.
+func (o *OverlaySystem) lstatLower(name string) (os.FileInfo, error) {
+	for path := filepath.Clean(name); ; path = filepath.Dir(path) {
+		if o.whiteouts[path] {
+			return nil, pathError("lstat", name, ErrPathNotFound)
+		}
+		if filepath.Dir(path) == path {
+			break
+		}
+	}
+	return o.lower.Lstat(name)
+}
+
+func (o *OverlaySystem) ReadDir(name string) ([]os.DirEntry, error) {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	upper, upperErr := o.upper.ReadDir(name)
+	lower, lowerErr := o.lower.ReadDir(name)
+	if _, err := o.lstatLower(name); err != nil {
+		lower, lowerErr = nil, err
+	}
+	if _, err := o.upper.Lstat(name); err == nil && upperErr != nil {
+		return nil, upperErr
+	}
+	if upperErr != nil && lowerErr != nil {
+		return nil, lowerErr
+	}
+	byName := make(map[string]os.DirEntry, len(upper)+len(lower))
+	for _, entry := range lower {
+		if !o.whiteouts[filepath.Join(name, entry.Name())] {
+			byName[entry.Name()] = entry
+		}
+	}
+	for _, entry := range upper {
+		byName[entry.Name()] = entry
+	}
+	entries := make([]os.DirEntry, 0, len(byName))
+	for _, entry := range byName {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+func (o *OverlaySystem) ReadFile(name string) ([]byte, error) {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	sys, err := o.layerOf(name)
+	if err != nil {
+		return nil, err
+	}
+	return sys.ReadFile(name)
+}
+
+func (o *OverlaySystem) Readlink(name string) (string, error) {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	sys, err := o.layerOf(name)
+	if err != nil {
+		return "", err
+	}
+	return sys.Readlink(name)
+}
+
+// layerOf returns the layer that provides name.
+func (o *OverlaySystem) layerOf(name string) (System, error) {
+	if _, err := o.upper.Lstat(name); err == nil {
+		return o.upper, nil
+	}
+	if _, err := o.lstatLower(name); err != nil {
+		return nil, err
+	}
+	return o.lower, nil
+}
+
+func (o *OverlaySystem) Glob(pattern string) (matches []string, err error) {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	upper, err := o.upper.Glob(pattern)
+	if err != nil {
+		return
+	}
+	lower, err := o.lower.Glob(pattern)
+	if err != nil {
+		return
+	}
+	seen := make(map[string]bool, len(upper))
+	for _, match := range upper {
+		seen[match] = true
+		matches = append(matches, match)
+	}
+	for _, match := range lower {
+		if _, err := o.lstatLower(match); err == nil && !seen[match] {
+			matches = append(matches, match)
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+func (o *OverlaySystem) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	if !isWriteFlag(flag) {
+		o.mutex.RLock()
+		defer o.mutex.RUnlock()
+		sys, err := o.layerOf(name)
+		if err != nil {
+			return nil, err
+		}
+		return sys.OpenFile(name, flag, perm)
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if _, err := o.lstat(name); err == nil {
+		if flag&os.O_EXCL != 0 {
+			return nil, pathError("open", name, ErrFileExists)
+		}
+		if err = o.copyUp(name); err != nil {
+			return nil, err
+		}
+	} else if err = o.copyUpDir(filepath.Dir(name), 0); err != nil {
+		return nil, err
+	}
+	return o.upper.OpenFile(name, flag, perm)
+}
+
+func (o *OverlaySystem) Chmod(name string, mode os.FileMode) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if err := o.copyUp(name); err != nil {
+		return err
+	}
+	return o.upper.Chmod(name, mode)
+}
+
+func (o *OverlaySystem) Chown(name string, uid, gid int) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if err := o.copyUp(name); err != nil {
+		return err
+	}
+	return o.upper.Chown(name, uid, gid)
+}
+
+func (o *OverlaySystem) Lchown(name string, uid, gid int) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if err := o.copyUp(name); err != nil {
+		return err
+	}
+	return o.upper.Lchown(name, uid, gid)
+}
+
+// Link creates newname as a hard link to oldname in the upper layer. Files in the lower layer are copied to the upper
+// layer individually, so hard links between them are not preserved.
+func (o *OverlaySystem) Link(oldname, newname string) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if _, err := o.lstat(newname); err == nil {
+		return linkError("link", oldname, newname, ErrFileExists)
+	}
+	if err := o.copyUp(oldname); err != nil {
+		return err
+	}
+	if err := o.copyUpDir(filepath.Dir(newname), 0); err != nil {
+		return err
+	}
+	return o.upper.Link(oldname, newname)
+}
+
+func (o *OverlaySystem) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if err := o.copyUp(name); err != nil {
+		return err
+	}
+	return o.upper.Chtimes(name, atime, mtime)
+}
+
+func (o *OverlaySystem) MkdirAll(path string, perm os.FileMode) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.mkdirAll(filepath.Clean(path), perm)
+}
+
+func (o *OverlaySystem) mkdirAll(path string, perm os.FileMode) error {
+	if info, err := o.lstat(path); err == nil {
+		if info.Mode()&os.ModeSymlink != 0 || info.IsDir() {
+			return o.copyUpDir(path, 0)
+		}
+		return pathError("mkdir", path, ErrNonDirectory)
+	}
+	if parent := filepath.Dir(path); parent != path {
+		if err := o.mkdirAll(parent, perm); err != nil {
+			return err
+		}
+	}
+	return o.upper.MkdirAll(path, perm)
+}
+
+func (o *OverlaySystem) Remove(name string) error { return o.remove(name, o.upper.Remove) }
+
+func (o *OverlaySystem) RemoveAll(path string) error {
+	err := o.remove(path, o.upper.RemoveAll)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (o *OverlaySystem) remove(name string, remove func(string) error) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	_, upperErr := o.upper.Lstat(name)
+	_, lowerErr := o.lstatLower(name)
+	if upperErr != nil && lowerErr != nil {
+		return pathError("remove", name, ErrPathNotFound)
+	}
+	if upperErr == nil {
+		if err := remove(name); err != nil {
+			return err
+		}
+	}
+	if lowerErr == nil {
+		o.whiteout(name)
+	}
+	return nil
+}
+
+func (o *OverlaySystem) Rename(oldpath, newpath string) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if _, err := o.lstat(oldpath); err != nil {
+		return err
+	}
+	if err := o.copyUpTree(oldpath); err != nil {
+		return err
+	}
+	if err := o.copyUpDir(filepath.Dir(newpath), 0); err != nil {
+		return err
+	}
+	_, oldErr := o.lstatLower(oldpath)
+	_, newErr := o.lstatLower(newpath)
+	if err := o.upper.Rename(oldpath, newpath); err != nil {
+		return err
+	}
+	if oldErr == nil {
+		o.whiteout(oldpath)
+	}
+	if newErr == nil {
+		o.whiteout(newpath)
+	}
+	return nil
+}
+
+func (o *OverlaySystem) Symlink(oldname, newname string) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if err := o.copyUpDir(filepath.Dir(newname), 0); err != nil {
+		return err
+	}
+	_, lowerErr := o.lstatLower(newname)
+	if err := o.upper.Symlink(oldname, newname); err != nil {
+		return err
+	}
+	if lowerErr == nil {
+		o.whiteout(newname)
+	}
+	return nil
+}
+
+// whiteout hides name, and everything beneath it, in the lower layer. Whiteouts beneath name are redundant, and are
+// dropped.
+func (o *OverlaySystem) whiteout(name string) {
+	name = filepath.Clean(name)
+	prefix := strings.TrimSuffix(name, string(os.PathSeparator)) + string(os.PathSeparator)
+	for path := range o.whiteouts {
+		if strings.HasPrefix(path, prefix) {
+			delete(o.whiteouts, path)
+		}
+	}
+	o.whiteouts[name] = true
+}
+
+// copyUp copies name from the lower layer to the upper layer, along with its ancestors, unless the upper layer already
+// has it. The contents of directories are not copied.
+func (o *OverlaySystem) copyUp(name string) error {
+	if _, err := o.upper.Lstat(name); err == nil {
+		return nil
+	}
+	info, err := o.lstatLower(name)
+	if err != nil {
+		return err
+	}
+	if err = o.copyUpDir(filepath.Dir(name), 0); err != nil {
+		return err
+	}
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := o.lower.Readlink(name)
+		if err != nil {
+			return err
+		}
+		return o.upper.Symlink(target, name)
+	case info.IsDir():
+		err = o.upper.MkdirAll(name, info.Mode().Perm())
+	default:
+		err = copySystemFile(o.lower, o.upper, name, info.Mode().Perm())
+	}
+	if err == nil {
+		err = o.upper.Chmod(name, info.Mode().Perm())
+	}
+	if err == nil {
+		err = o.upper.Chtimes(name, info.ModTime(), info.ModTime())
+	}
+	if uid, gid, ok := fileOwner(info); ok && err == nil {
+		err = o.upper.Lchown(name, uid, gid)
+	}
+	return err
+}
+
+// copySystemFile copies the contents of the file at name from one System to another.
+func copySystemFile(from, to System, name string, perm os.FileMode) error {
+	reader, err := from.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	writer, err := to.OpenFile(name, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(writer, reader)
+	if err == nil {
+		err = writer.Close()
+	} else {
+		_ = writer.Close()
+	}
+	return err
+}
+
+// copyUpDir makes sure dir exists as a directory in the upper layer, copying it and its ancestors from the lower layer
+// as required. Symlinks are copied along with the directories they point to.
+func (o *OverlaySystem) copyUpDir(dir string, hops int) error {
+	if hops > maxLinkHops {
+		return pathError("mkdir", dir, ErrLinkLoop)
+	}
+	if parent := filepath.Dir(dir); parent != dir {
+		if err := o.copyUpDir(parent, hops); err != nil {
+			return err
+		}
+	}
+	if err := o.copyUp(dir); err != nil {
+		return err
+	}
+	info, err := o.upper.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := o.upper.Readlink(dir)
+		if err != nil {
+			return err
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(dir), target)
+		}
+		return o.copyUpDir(target, hops+1)
+	case !info.IsDir():
+		return pathError("mkdir", dir, ErrNonDirectory)
+	}
+	return nil
+}
+
+// copyUpTree copies name and everything beneath it from the lower layer to the upper layer.
+func (o *OverlaySystem) copyUpTree(name string) error {
+	if err := o.copyUp(name); err != nil {
+		return err
+	}
+	info, err := o.upper.Lstat(name)
+	if err != nil || !info.IsDir() {
+		return err
+	}
+	if _, err = o.lstatLower(name); err != nil {
+		return nil
+	}
+	entries, err := o.lower.ReadDir(name)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		child := filepath.Join(name, entry.Name())
+		if _, err = o.lstatLower(child); err != nil {
+			continue
+		}
+		if err = o.copyUpTree(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package paths_test
+
+import (
+	. "github.com/hx/golib/paths"
+	. "github.com/hx/golib/testing"
+	"testing"
+)
+
+func TestOverlaySystem(t *testing.T) {
+	lower := NewTree().Join(t.TempDir())
+	lower.Join("a").MustWriteString("a")
+	lower.Join("b").MustWriteString("b")
+	lower.Join("sub", "c").MustWriteString("c")
+
+	overlay := NewOverlaySystem(NewVirtualSystem(), LocalSystem)
+	dir := NewTreeWithSystem(overlay).Join(lower.String())
+
+	names := func(dir *Path) (names []string) {
+		for _, child := range dir.MustChildren() {
+			names = append(names, child.Base())
+		}
+		return
+	}
+
+	dir.Join("a").MustWriteString("A")
+	dir.Join("new").MustWriteString("new")
+	dir.Join("b").MustDelete()
+	Ok(t, dir.Join("sub").Rename(dir.Join("moved")))
+	dir.Join("sub").MustMake()
+
+	Equals(t, "A", dir.Join("a").MustReadString())
+	Equals(t, "c", dir.Join("moved", "c").MustReadString())
+	Equals(t, false, dir.Join("b").Exists())
+	Equals(t, []string{"a", "moved", "new", "sub"}, names(dir))
+	Equals(t, 0, len(dir.Join("sub").MustChildren()))
+	Equals(t, []string{lower.Join("b").String(), lower.Join("sub").String()}, overlay.Whiteouts())
+
+	Equals(t, "a", lower.Join("a").MustReadString())
+	Equals(t, []string{"a", "b", "sub"}, names(lower))
+
+	Ok(t, overlay.Commit())
+	Equals(t, "A", lower.Join("a").MustReadString())
+	Equals(t, "c", lower.Join("moved", "c").MustReadString())
+	Equals(t, []string{"a", "moved", "new", "sub"}, names(lower))
+	Equals(t, 0, len(lower.Join("sub").MustChildren()))
+}
@@ -0,0 +1,48 @@
+package paths
+
+import "os"
+
+type ChownEvent struct{ Event }
+
+// Chown changes the owner of p, following symlinks. A uid or gid of -1 leaves that value unchanged.
+func (p *Path) Chown(uid, gid int) error { return p.chown(p.tree.sys.Chown, uid, gid) }
+
+// Lchown changes the owner of p, without following symlinks. A uid or gid of -1 leaves that value unchanged.
+func (p *Path) Lchown(uid, gid int) error { return p.chown(p.tree.sys.Lchown, uid, gid) }
+
+func (p *Path) MustChown(uid, gid int)  { must(p.Chown(uid, gid)) }
+func (p *Path) MustLchown(uid, gid int) { must(p.Lchown(uid, gid)) }
+
+func (p *Path) chown(chown func(string, int, int) error, uid, gid int) error {
+	if err := chown(p.path, uid, gid); err != nil {
+		return err
+	}
+	p.tree.dispatch(ChownEvent{newEvent(p)})
+	return nil
+}
+
+// Owner returns the user and group IDs of the owner of p, without following symlinks. If p's System doesn't report
+// ownership, ErrUnsupported is returned.
+func (p *Path) Owner() (uid, gid int, err error) {
+	info, err := p.Stat()
+	if err != nil {
+		return
+	}
+	uid, gid, ok := fileOwner(info)
+	if !ok {
+		return -1, -1, ErrUnsupported
+	}
+	return
+}
+
+// owned is implemented by the FileInfo values returned by VirtualSystem.
+type owned interface{ owner() (uid, gid int) }
+
+func fileOwner(info os.FileInfo) (uid, gid int, ok bool) {
+	info = unwrapInfo(info)
+	if o, isOwned := info.(owned); isOwned {
+		uid, gid = o.owner()
+		return uid, gid, true
+	}
+	return sysOwner(info.Sys())
+}
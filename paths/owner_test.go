@@ -0,0 +1,85 @@
+package paths_test
+
+import (
+	. "github.com/hx/golib/paths"
+	. "github.com/hx/golib/testing"
+	"os"
+	"testing"
+)
+
+func TestPath_Chown(t *testing.T) {
+	tree := NewTreeWithSystem(NewVirtualSystem())
+	var events []Event
+	tree.Subscribe(func(event Event) { events = append(events, event) })
+	file := tree.Join("file").MustTouch()
+	link := tree.Join("link")
+	file.MustSymlinkTo(link)
+
+	file.MustChown(1000, 100)
+	uid, gid, err := file.Owner()
+	Ok(t, err)
+	Equals(t, []int{1000, 100}, []int{uid, gid})
+
+	link.MustChown(-1, 200)
+	uid, gid, _ = file.Owner()
+	Equals(t, []int{1000, 200}, []int{uid, gid})
+	uid, gid, _ = link.Owner()
+	Equals(t, []int{0, 0}, []int{uid, gid})
+
+	link.MustLchown(5, 6)
+	uid, gid, _ = link.Owner()
+	Equals(t, []int{5, 6}, []int{uid, gid})
+	uid, gid, _ = file.Owner()
+	Equals(t, []int{1000, 200}, []int{uid, gid})
+
+	var chowns int
+	for _, event := range events {
+		if _, ok := event.(ChownEvent); ok {
+			chowns++
+		}
+	}
+	Equals(t, 3, chowns)
+}
+
+func TestPath_Owner_Local(t *testing.T) {
+	if LocalSystem.Root() != "/" {
+		t.Skip("ownership is not reported on this platform")
+	}
+	uid, gid, err := NewTree().Join(t.TempDir()).Owner()
+	Ok(t, err)
+	Equals(t, []int{os.Getuid(), os.Getgid()}, []int{uid, gid})
+}
+
+func TestPath_LinkTo(t *testing.T) {
+	eachSystem(t, func(t *testing.T, tree *Tree, dir *Path) {
+		var events []Event
+		tree.Subscribe(func(event Event) { events = append(events, event) })
+		original := dir.Join("original")
+		original.MustWriteString("one")
+		link := dir.Join("link")
+		original.MustLinkTo(link)
+		_, isLinkEvent := events[len(events)-1].(LinkEvent)
+		Assert(t, isLinkEvent, "expected a LinkEvent, got %T", events[len(events)-1])
+
+		link.MustWriteString("two")
+		Equals(t, "two", original.MustReadString())
+		link.MustChmod(0600)
+		Equals(t, os.FileMode(0600), original.MustStat().Mode())
+		Assert(t, original.LinkTo(link) != nil, "linking over an existing file should fail")
+
+		original.MustDelete()
+		Equals(t, "two", link.MustReadString())
+	})
+}
+
+func TestVirtualSystem_Clone_HardLinks(t *testing.T) {
+	sys := NewVirtualSystem()
+	tree := NewTreeWithSystem(sys)
+	tree.Join("a").MustWriteString("a")
+	tree.Join("a").MustLinkTo(tree.Join("b"))
+
+	clone := NewTreeWithSystem(sys.Clone())
+	clone.Join("b").MustWriteString("changed")
+	Equals(t, "changed", clone.Join("a").MustReadString())
+	Equals(t, "a", tree.Join("a").MustReadString())
+}
@@ -65,13 +65,19 @@ func (p *Path) MustTouch() *Path {
 	return p
 }
 
+// IsDir reports whether p is a directory, or a symlink to one.
 func (p *Path) IsDir() bool {
-	s, err := p.Stat()
+	s, err := p.StatFollowingLinks()
 	return err == nil && s.IsDir()
 }
 
+// IsNonDir reports whether p exists and is not a directory. Symlinks are followed, so a symlink to a directory is not
+// a non-directory, but a broken symlink is.
 func (p *Path) IsNonDir() bool {
 	s, err := p.Stat()
+	if err == nil && s.Mode()&os.ModeSymlink != 0 {
+		return !p.IsDir()
+	}
 	return err == nil && !s.IsDir()
 }
 
@@ -91,6 +97,7 @@ func (p *Path) UserHome() (*Path, error) {
 	}
 	return p.Join(u.HomeDir), nil
 }
+func (p *Path) MustUserHome() *Path { return must1(p.UserHome()).(*Path) }
 
 func (p *Path) Size() (int64, error) {
 	stat, err := p.Stat()
@@ -0,0 +1,65 @@
+package paths
+
+import (
+	"os"
+	"time"
+)
+
+// ReadOnlySystem wraps another System, and refuses every call that would modify it with ErrNotWritable.
+type ReadOnlySystem struct{ System }
+
+func NewReadOnlySystem(sys System) *ReadOnlySystem { return &ReadOnlySystem{sys} }
+
+func (r *ReadOnlySystem) FoldsCase() bool { return foldsCase(r.System) }
+
+func (r *ReadOnlySystem) Chmod(name string, _ os.FileMode) error {
+	return pathError("chmod", name, ErrNotWritable)
+}
+
+func (r *ReadOnlySystem) Chown(name string, _, _ int) error {
+	return pathError("chown", name, ErrNotWritable)
+}
+
+func (r *ReadOnlySystem) Chtimes(name string, _, _ time.Time) error {
+	return pathError("chtimes", name, ErrNotWritable)
+}
+
+func (r *ReadOnlySystem) Lchown(name string, _, _ int) error {
+	return pathError("lchown", name, ErrNotWritable)
+}
+
+func (r *ReadOnlySystem) Link(oldname, newname string) error {
+	return linkError("link", oldname, newname, ErrNotWritable)
+}
+
+func (r *ReadOnlySystem) MkdirAll(path string, _ os.FileMode) error {
+	return pathError("mkdir", path, ErrNotWritable)
+}
+
+func (r *ReadOnlySystem) Remove(name string) error {
+	return pathError("remove", name, ErrNotWritable)
+}
+
+func (r *ReadOnlySystem) RemoveAll(path string) error {
+	return pathError("remove", path, ErrNotWritable)
+}
+
+func (r *ReadOnlySystem) Rename(oldpath, newpath string) error {
+	return linkError("rename", oldpath, newpath, ErrNotWritable)
+}
+
+func (r *ReadOnlySystem) Symlink(oldname, newname string) error {
+	return linkError("symlink", oldname, newname, ErrNotWritable)
+}
+
+func (r *ReadOnlySystem) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	if isWriteFlag(flag) {
+		return nil, pathError("open", name, ErrNotWritable)
+	}
+	return r.System.OpenFile(name, flag, perm)
+}
+
+// isWriteFlag reports whether a file opened with flag could be modified.
+func isWriteFlag(flag int) bool {
+	return flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_APPEND|os.O_TRUNC) != 0
+}
@@ -0,0 +1,25 @@
+package paths_test
+
+import (
+	"errors"
+	. "github.com/hx/golib/paths"
+	. "github.com/hx/golib/testing"
+	"testing"
+)
+
+func TestReadOnlySystem(t *testing.T) {
+	sys := NewVirtualSystem()
+	NewTreeWithSystem(sys).Join("file").MustWriteString("contents")
+	tree := NewTreeWithSystem(NewReadOnlySystem(sys))
+	notWritable := func(err error) {
+		t.Helper()
+		Assert(t, errors.Is(err, ErrNotWritable), "expected ErrNotWritable, got %v", err)
+	}
+	Equals(t, "contents", tree.Join("file").MustReadString())
+	notWritable(tree.Join("file").WriteString("changed"))
+	notWritable(tree.Join("file").Delete())
+	notWritable(tree.Join("file").Chmod(0600))
+	notWritable(tree.Join("file").Rename(tree.Join("moved")))
+	notWritable(tree.Join("dir").Make())
+	Equals(t, "contents", tree.Join("file").MustReadString())
+}
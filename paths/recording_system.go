@@ -0,0 +1,417 @@
+package paths
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+)
+
+// RecordedCall is a call made through a RecordingSystem, or through a file opened by one.
+type RecordedCall struct {
+	// The name of the System method, e.g. "MkdirAll", or, for calls made on open files, the name of the File method
+	// prefixed with "File.", e.g. "File.Write".
+	Method string `json:"method"`
+
+	// The path being operated on. For Rename, this is the old path, and for Link and Symlink, it's the path of the new
+	// link. For Glob, it's the pattern.
+	Path string `json:"path,omitempty"`
+
+	// The new path for Rename, and the existing path for Link and Symlink.
+	Target string `json:"target,omitempty"`
+
+	// The mode passed to Chmod, MkdirAll or OpenFile.
+	Mode os.FileMode `json:"mode,omitempty"`
+
+	// The flags passed to OpenFile.
+	Flag int `json:"flag,omitempty"`
+
+	// The owner passed to Chown or Lchown.
+	Uid int `json:"uid,omitempty"`
+	Gid int `json:"gid,omitempty"`
+
+	// The times passed to Chtimes.
+	Atime time.Time `json:"atime"`
+	Mtime time.Time `json:"mtime"`
+
+	// The bytes passed to File.Write.
+	Data []byte `json:"data,omitempty"`
+
+	// The arguments to File.Seek.
+	Offset int64 `json:"offset,omitempty"`
+	Whence int   `json:"whence,omitempty"`
+
+	// Identifies the file opened by OpenFile, and the file operated on by File methods. Files are numbered from 1.
+	File int `json:"file,omitempty"`
+
+	// A summary of the value returned by the call, if any. Lstat and Stat return a RecordedInfo; ReadDir and Glob return names;
+	// ReadFile, File.Read and File.Write return the number of bytes read or written; File.Seek returns the new offset.
+	Result interface{} `json:"result,omitempty"`
+
+	// The message of the error returned by the call, if any.
+	Error string `json:"error,omitempty"`
+
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+}
+
+func (c *RecordedCall) String() string {
+	s := c.Method
+	if c.File != 0 {
+		s += fmt.Sprintf(" #%d", c.File)
+	}
+	if c.Path != "" {
+		s += " " + c.Path
+	}
+	if c.Target != "" {
+		s += " " + c.Target
+	}
+	if c.Error != "" {
+		s += ": " + c.Error
+	}
+	return s
+}
+
+// RecordedInfo summarises the os.FileInfo returned by Lstat or Stat.
+type RecordedInfo struct {
+	Name    string      `json:"name"`
+	Size    int64       `json:"size"`
+	Mode    os.FileMode `json:"mode"`
+	ModTime time.Time   `json:"modTime"`
+}
+
+// RecordingSystem wraps another System, and records every call made through it, along with calls made on the files it
+// opens. Join and Root are not recorded, since they don't touch the underlying system.
+type RecordingSystem struct {
+	sys   System
+	calls []*RecordedCall
+	files int
+	mutex sync.Mutex
+}
+
+func NewRecordingSystem(sys System) *RecordingSystem { return &RecordingSystem{sys: sys} }
+
+// Calls returns the calls recorded so far, in the order they finished.
+func (r *RecordingSystem) Calls() []RecordedCall {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	calls := make([]RecordedCall, len(r.calls))
+	for i, call := range r.calls {
+		calls[i] = *call
+	}
+	return calls
+}
+
+// Reset clears the recorded calls.
+func (r *RecordingSystem) Reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.calls = nil
+}
+
+// WriteJSON writes the recorded calls to writer as JSON lines.
+func (r *RecordingSystem) WriteJSON(writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	for _, call := range r.Calls() {
+		if err := encoder.Encode(call); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Replay repeats the recorded calls that modified the file system against sys. See ReplayCalls.
+func (r *RecordingSystem) Replay(sys System) error { return ReplayCalls(sys, r.Calls()) }
+
+// ReadRecordedCalls reads calls written by RecordingSystem.WriteJSON.
+func ReadRecordedCalls(reader io.Reader) (calls []RecordedCall, err error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, 1<<30)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var call RecordedCall
+		if err = json.Unmarshal(scanner.Bytes(), &call); err != nil {
+			return
+		}
+		calls = append(calls, call)
+	}
+	return calls, scanner.Err()
+}
+
+// ReplayCalls repeats calls that modified the file system against sys, in order. Calls that only read from the file
+// system are skipped, as are calls that failed when they were recorded. Replay stops at the first error.
+func ReplayCalls(sys System, calls []RecordedCall) (err error) {
+	files := make(map[int]File)
+	defer func() {
+		for _, file := range files {
+			_ = file.Close()
+		}
+	}()
+	for _, call := range calls {
+		if call.Error != "" {
+			continue
+		}
+		file := files[call.File]
+		switch call.Method {
+		case "Chmod":
+			err = sys.Chmod(call.Path, call.Mode)
+		case "Chown":
+			err = sys.Chown(call.Path, call.Uid, call.Gid)
+		case "Lchown":
+			err = sys.Lchown(call.Path, call.Uid, call.Gid)
+		case "Link":
+			err = sys.Link(call.Target, call.Path)
+		case "Chtimes":
+			err = sys.Chtimes(call.Path, call.Atime, call.Mtime)
+		case "MkdirAll":
+			err = sys.MkdirAll(call.Path, call.Mode)
+		case "Remove":
+			err = sys.Remove(call.Path)
+		case "RemoveAll":
+			err = sys.RemoveAll(call.Path)
+		case "Rename":
+			err = sys.Rename(call.Path, call.Target)
+		case "Symlink":
+			err = sys.Symlink(call.Target, call.Path)
+		case "OpenFile":
+			if isWriteFlag(call.Flag) {
+				files[call.File], err = sys.OpenFile(call.Path, call.Flag, call.Mode)
+			}
+		case "File.Write":
+			if file != nil {
+				_, err = file.Write(call.Data)
+			}
+		case "File.Seek":
+			if file != nil {
+				_, err = file.Seek(call.Offset, call.Whence)
+			}
+		case "File.Close":
+			if file != nil {
+				delete(files, call.File)
+				err = file.Close()
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (r *RecordingSystem) finish(call *RecordedCall, result interface{}, err error) {
+	call.Duration = time.Since(call.Start)
+	call.Result = result
+	if err != nil {
+		call.Error = err.Error()
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+func (r *RecordingSystem) Join(elem ...string) string { return r.sys.Join(elem...) }
+func (r *RecordingSystem) Root() string               { return r.sys.Root() }
+func (r *RecordingSystem) FoldsCase() bool            { return foldsCase(r.sys) }
+
+func (r *RecordingSystem) Chmod(name string, mode os.FileMode) (err error) {
+	call := &RecordedCall{Method: "Chmod", Path: name, Mode: mode, Start: time.Now()}
+	err = r.sys.Chmod(name, mode)
+	r.finish(call, nil, err)
+	return
+}
+
+func (r *RecordingSystem) Chown(name string, uid, gid int) (err error) {
+	call := &RecordedCall{Method: "Chown", Path: name, Uid: uid, Gid: gid, Start: time.Now()}
+	err = r.sys.Chown(name, uid, gid)
+	r.finish(call, nil, err)
+	return
+}
+
+func (r *RecordingSystem) Chtimes(name string, atime time.Time, mtime time.Time) (err error) {
+	call := &RecordedCall{Method: "Chtimes", Path: name, Atime: atime, Mtime: mtime, Start: time.Now()}
+	err = r.sys.Chtimes(name, atime, mtime)
+	r.finish(call, nil, err)
+	return
+}
+
+func (r *RecordingSystem) CurrentUser() (u *user.User, err error) {
+	call := &RecordedCall{Method: "CurrentUser", Start: time.Now()}
+	u, err = r.sys.CurrentUser()
+	if u != nil {
+		r.finish(call, u.Username, err)
+	} else {
+		r.finish(call, nil, err)
+	}
+	return
+}
+
+func (r *RecordingSystem) Getwd() (dir string, err error) {
+	call := &RecordedCall{Method: "Getwd", Start: time.Now()}
+	dir, err = r.sys.Getwd()
+	r.finish(call, dir, err)
+	return
+}
+
+func (r *RecordingSystem) Glob(pattern string) (matches []string, err error) {
+	call := &RecordedCall{Method: "Glob", Path: pattern, Start: time.Now()}
+	matches, err = r.sys.Glob(pattern)
+	r.finish(call, matches, err)
+	return
+}
+
+func (r *RecordingSystem) Lchown(name string, uid, gid int) (err error) {
+	call := &RecordedCall{Method: "Lchown", Path: name, Uid: uid, Gid: gid, Start: time.Now()}
+	err = r.sys.Lchown(name, uid, gid)
+	r.finish(call, nil, err)
+	return
+}
+
+func (r *RecordingSystem) Link(oldname, newname string) (err error) {
+	call := &RecordedCall{Method: "Link", Path: newname, Target: oldname, Start: time.Now()}
+	err = r.sys.Link(oldname, newname)
+	r.finish(call, nil, err)
+	return
+}
+
+func (r *RecordingSystem) Lstat(name string) (info os.FileInfo, err error) {
+	call := &RecordedCall{Method: "Lstat", Path: name, Start: time.Now()}
+	info, err = r.sys.Lstat(name)
+	r.finish(call, recordedInfo(info), err)
+	return
+}
+
+func recordedInfo(info os.FileInfo) interface{} {
+	if info == nil {
+		return nil
+	}
+	return &RecordedInfo{info.Name(), info.Size(), info.Mode(), info.ModTime()}
+}
+
+func (r *RecordingSystem) MkdirAll(path string, perm os.FileMode) (err error) {
+	call := &RecordedCall{Method: "MkdirAll", Path: path, Mode: perm, Start: time.Now()}
+	err = r.sys.MkdirAll(path, perm)
+	r.finish(call, nil, err)
+	return
+}
+
+func (r *RecordingSystem) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	call := &RecordedCall{Method: "OpenFile", Path: name, Flag: flag, Mode: perm, Start: time.Now()}
+	file, err := r.sys.OpenFile(name, flag, perm)
+	if err != nil {
+		r.finish(call, nil, err)
+		return nil, err
+	}
+	r.mutex.Lock()
+	r.files++
+	call.File = r.files
+	r.mutex.Unlock()
+	r.finish(call, nil, nil)
+	return &recordingFile{r, file, call.File}, nil
+}
+
+func (r *RecordingSystem) ReadDir(name string) (entries []os.DirEntry, err error) {
+	call := &RecordedCall{Method: "ReadDir", Path: name, Start: time.Now()}
+	entries, err = r.sys.ReadDir(name)
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	r.finish(call, names, err)
+	return
+}
+
+func (r *RecordingSystem) ReadFile(name string) (b []byte, err error) {
+	call := &RecordedCall{Method: "ReadFile", Path: name, Start: time.Now()}
+	b, err = r.sys.ReadFile(name)
+	r.finish(call, len(b), err)
+	return
+}
+
+func (r *RecordingSystem) Readlink(name string) (target string, err error) {
+	call := &RecordedCall{Method: "Readlink", Path: name, Start: time.Now()}
+	target, err = r.sys.Readlink(name)
+	r.finish(call, target, err)
+	return
+}
+
+func (r *RecordingSystem) Remove(name string) (err error) {
+	call := &RecordedCall{Method: "Remove", Path: name, Start: time.Now()}
+	err = r.sys.Remove(name)
+	r.finish(call, nil, err)
+	return
+}
+
+func (r *RecordingSystem) RemoveAll(path string) (err error) {
+	call := &RecordedCall{Method: "RemoveAll", Path: path, Start: time.Now()}
+	err = r.sys.RemoveAll(path)
+	r.finish(call, nil, err)
+	return
+}
+
+func (r *RecordingSystem) Rename(oldpath, newpath string) (err error) {
+	call := &RecordedCall{Method: "Rename", Path: oldpath, Target: newpath, Start: time.Now()}
+	err = r.sys.Rename(oldpath, newpath)
+	r.finish(call, nil, err)
+	return
+}
+
+func (r *RecordingSystem) Stat(name string) (info os.FileInfo, err error) {
+	call := &RecordedCall{Method: "Stat", Path: name, Start: time.Now()}
+	info, err = r.sys.Stat(name)
+	r.finish(call, recordedInfo(info), err)
+	return
+}
+
+func (r *RecordingSystem) SupportsSymlinks() (supported bool) {
+	call := &RecordedCall{Method: "SupportsSymlinks", Start: time.Now()}
+	supported = r.sys.SupportsSymlinks()
+	r.finish(call, supported, nil)
+	return
+}
+
+func (r *RecordingSystem) Symlink(oldname, newname string) (err error) {
+	call := &RecordedCall{Method: "Symlink", Path: newname, Target: oldname, Start: time.Now()}
+	err = r.sys.Symlink(oldname, newname)
+	r.finish(call, nil, err)
+	return
+}
+
+type recordingFile struct {
+	sys  *RecordingSystem
+	file File
+	id   int
+}
+
+func (f *recordingFile) Read(b []byte) (n int, err error) {
+	call := &RecordedCall{Method: "File.Read", File: f.id, Start: time.Now()}
+	n, err = f.file.Read(b)
+	f.sys.finish(call, n, err)
+	return
+}
+
+func (f *recordingFile) Write(b []byte) (n int, err error) {
+	call := &RecordedCall{Method: "File.Write", File: f.id, Data: append([]byte{}, b...), Start: time.Now()}
+	n, err = f.file.Write(b)
+	f.sys.finish(call, n, err)
+	return
+}
+
+func (f *recordingFile) Seek(offset int64, whence int) (n int64, err error) {
+	call := &RecordedCall{Method: "File.Seek", File: f.id, Offset: offset, Whence: whence, Start: time.Now()}
+	n, err = f.file.Seek(offset, whence)
+	f.sys.finish(call, n, err)
+	return
+}
+
+func (f *recordingFile) Close() (err error) {
+	call := &RecordedCall{Method: "File.Close", File: f.id, Start: time.Now()}
+	err = f.file.Close()
+	f.sys.finish(call, nil, err)
+	return
+}
@@ -0,0 +1,37 @@
+package paths_test
+
+import (
+	"bytes"
+	. "github.com/hx/golib/paths"
+	. "github.com/hx/golib/testing"
+	"os"
+	"testing"
+)
+
+func TestRecordingSystem(t *testing.T) {
+	recorder := NewRecordingSystem(NewVirtualSystem())
+	tree := NewTreeWithSystem(recorder)
+	tree.Join("dir", "file").MustWriteString("contents")
+	tree.Join("dir", "file").MustChmod(0600)
+	tree.Join("dir", "file").MustRename(tree.Join("dir", "moved"))
+
+	buf := new(bytes.Buffer)
+	Ok(t, recorder.WriteJSON(buf))
+	calls, err := ReadRecordedCalls(buf)
+	Ok(t, err)
+	Equals(t, len(recorder.Calls()), len(calls))
+
+	replayed := NewVirtualSystem()
+	Ok(t, ReplayCalls(replayed, calls))
+	Equals(t, "contents", NewTreeWithSystem(replayed).Join("dir", "moved").MustReadString())
+	Equals(t, os.FileMode(0600), NewTreeWithSystem(replayed).Join("dir", "moved").MustStat().Mode())
+	Equals(t, false, NewTreeWithSystem(replayed).Join("dir", "file").Exists())
+
+	recorder.Reset()
+	Ok(t, tree.Join("dir", "moved").WriteStringUnlessEqual("contents"))
+	for _, call := range recorder.Calls() {
+		Assert(t, call.Method != "OpenFile" || call.Flag == os.O_RDONLY, "unexpected call: %s", &call)
+		Assert(t, call.Method != "File.Write", "unexpected call: %s", &call)
+	}
+	Assert(t, len(recorder.Calls()) > 0, "expected calls to be recorded")
+}
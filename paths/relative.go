@@ -0,0 +1,118 @@
+package paths
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// caseFoldingSystem is implemented by systems whose names are compared without regard to case, such as LocalSystem on
+// Windows. Systems that don't implement it, such as VirtualSystem, are case-sensitive.
+type caseFoldingSystem interface{ FoldsCase() bool }
+
+func foldsCase(sys System) bool {
+	folder, ok := sys.(caseFoldingSystem)
+	return ok && folder.FoldsCase()
+}
+
+// Rel returns the relative path that leads from base to p, such that base.Join(rel) is p. Like filepath.Rel, it works
+// lexically, and fails if p and base are on different volumes. Names are compared, and the result joined, using the
+// rules of p's System.
+func (p *Path) Rel(base *Path) (string, error) {
+	if !p.samePart(p.volume(), base.volume()) {
+		return "", fmt.Errorf("Rel: can't make %s relative to %s", p.path, base.path)
+	}
+	parts, baseParts := p.Split(), base.Split()
+	common := 0
+	for common < len(parts) && common < len(baseParts) && p.samePart(parts[common], baseParts[common]) {
+		common++
+	}
+	var rel []string
+	for range baseParts[common:] {
+		rel = append(rel, "..")
+	}
+	rel = append(rel, parts[common:]...)
+	if len(rel) == 0 {
+		return ".", nil
+	}
+	return p.tree.sys.Join(rel...), nil
+}
+func (p *Path) MustRel(base *Path) string { return must1(p.Rel(base)).(string) }
+
+// IsWithin reports whether p is beneath ancestor. A path is not within itself. Symlinks are not resolved.
+func (p *Path) IsWithin(ancestor *Path) bool {
+	parts, ancestorParts := p.Split(), ancestor.Split()
+	if len(parts) <= len(ancestorParts) || !p.samePart(p.volume(), ancestor.volume()) {
+		return false
+	}
+	for i, part := range ancestorParts {
+		if !p.samePart(part, parts[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// CommonAncestor returns the deepest path that is, or contains, p and every one of others. If they're on different
+// volumes, it returns nil.
+func (p *Path) CommonAncestor(others ...*Path) *Path {
+	common := p.Split()
+	for _, other := range others {
+		if !p.samePart(p.volume(), other.volume()) {
+			return nil
+		}
+		parts := other.Split()
+		if len(parts) < len(common) {
+			common = common[:len(parts)]
+		}
+		for i, part := range common {
+			if !p.samePart(part, parts[i]) {
+				common = common[:i]
+				break
+			}
+		}
+	}
+	return p.Join(p.root()).Join(common...)
+}
+
+// Split returns the names of the directories leading to p, followed by the name of p itself, separated according to
+// p's System. The volume name and root are excluded, so the root splits into no names at all.
+func (p *Path) Split() (parts []string) {
+	for _, part := range strings.Split(p.path[len(p.volume()):], p.separator()) {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return
+}
+
+// Ancestors returns the parent of p, its parent, and so on, up to and including the root. The root has no ancestors.
+func (p *Path) Ancestors() (ancestors Paths) {
+	for child, parent := p, p.Parent(); parent.path != child.path; child, parent = parent, parent.Parent() {
+		ancestors = append(ancestors, parent)
+	}
+	return
+}
+
+// volume returns the volume name of p. Volume names are recognised using the host's rules, as they are when paths are
+// cleaned.
+func (p *Path) volume() string { return filepath.VolumeName(p.path) }
+
+// separator returns the path separator of p's System, which is the last character of its root.
+func (p *Path) separator() string {
+	root := p.tree.sys.Root()
+	return root[len(root)-1:]
+}
+
+// root returns the root of p's volume.
+func (p *Path) root() string {
+	return p.volume() + p.separator()
+}
+
+// samePart reports whether two path components are the same, ignoring case if p's System does.
+func (p *Path) samePart(a, b string) bool {
+	if foldsCase(p.tree.sys) {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
@@ -0,0 +1,58 @@
+package paths_test
+
+import (
+	. "github.com/hx/golib/paths"
+	. "github.com/hx/golib/testing"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestPath_Rel(t *testing.T) {
+	tree := NewTreeWithSystem(NewVirtualSystem())
+	out := tree.Join("build", "out")
+	Equals(t, filepath.Join("css", "site.css"), out.Join("css", "site.css").MustRel(out))
+	Equals(t, filepath.Join("..", "..", "src"), tree.Join("src").MustRel(out))
+	Equals(t, ".", out.MustRel(out))
+	Equals(t, filepath.Join("..", "OUT", "x"), tree.Join("build", "OUT", "x").MustRel(out))
+}
+
+func TestPath_IsWithin(t *testing.T) {
+	tree := NewTreeWithSystem(NewVirtualSystem())
+	out := tree.Join("build", "out")
+	Assert(t, out.Join("css", "site.css").IsWithin(out), "a descendant should be within")
+	Assert(t, out.IsWithin(tree.Path), "everything should be within the root")
+	Assert(t, !out.IsWithin(out), "a path should not be within itself")
+	Assert(t, !out.Join("..", "outside").IsWithin(out), "a sibling should not be within")
+	Assert(t, !tree.Join("build", "output").IsWithin(out), "a path with a common prefix should not be within")
+	Assert(t, !tree.IsWithin(out), "an ancestor should not be within")
+	Assert(t, !tree.Join("build", "OUT", "x").IsWithin(out), "a VirtualSystem should compare names case-sensitively")
+
+	local := NewTree()
+	Equals(t, runtime.GOOS == "windows", local.Join("build", "OUT", "x").IsWithin(local.Join("build", "out")))
+}
+
+func TestPath_CommonAncestor(t *testing.T) {
+	tree := NewTreeWithSystem(NewVirtualSystem())
+	src := tree.Join("project", "src")
+	Equals(t, src.String(), src.Join("a", "b").CommonAncestor(src.Join("a", "c"), src.Join("d")).String())
+	Equals(t, src.String(), src.CommonAncestor(src.Join("a")).String())
+	Equals(t, src.String(), src.CommonAncestor().String())
+	Equals(t, tree.String(), src.CommonAncestor(tree.Join("other")).String())
+}
+
+func TestPath_Split(t *testing.T) {
+	tree := NewTreeWithSystem(NewVirtualSystem())
+	Equals(t, []string{"a", "b", "c"}, tree.Join("a", "b", "c").Split())
+	Equals(t, 0, len(tree.Split()))
+}
+
+func TestPath_Ancestors(t *testing.T) {
+	tree := NewTreeWithSystem(NewVirtualSystem())
+	var names []string
+	for _, ancestor := range tree.Join("a", "b", "c").Ancestors() {
+		names = append(names, ancestor.String())
+	}
+	Equals(t, []string{tree.Join("a", "b").String(), tree.Join("a").String(), tree.String()}, names)
+	Equals(t, 0, len(tree.Ancestors()))
+}
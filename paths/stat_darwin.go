@@ -0,0 +1,16 @@
+package paths
+
+import (
+	"syscall"
+	"time"
+)
+
+func sysMetadata(sys interface{}) (m fileMetadata, ok bool) {
+	stat, ok := sys.(*syscall.Stat_t)
+	if ok {
+		m.accessed = time.Unix(stat.Atimespec.Unix())
+		m.links = uint64(stat.Nlink)
+		m.id = FileID{uint64(stat.Dev), stat.Ino}
+	}
+	return
+}
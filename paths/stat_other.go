@@ -0,0 +1,6 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package paths
+
+func sysMetadata(interface{}) (m fileMetadata, ok bool) { return }
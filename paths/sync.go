@@ -0,0 +1,175 @@
+package paths
+
+import "os"
+
+type SyncAction int
+
+const (
+	SyncCreate SyncAction = iota
+	SyncOverwrite
+	SyncDelete
+)
+
+func (a SyncAction) String() string {
+	switch a {
+	case SyncCreate:
+		return "create"
+	case SyncOverwrite:
+		return "overwrite"
+	case SyncDelete:
+		return "delete"
+	}
+	return "unknown"
+}
+
+// SyncOp is a single operation required to bring a target path in line with its source. Source is nil for deletions.
+type SyncOp struct {
+	Action SyncAction
+	Source *Path
+	Target *Path
+}
+
+func (o SyncOp) String() string { return o.Action.String() + " " + o.Target.String() }
+
+// SyncPlan is a list of operations that will make a target directory mirror its source, in the order they should be
+// applied.
+type SyncPlan []SyncOp
+
+// PlanSyncTo returns the operations SyncTo would perform to make target identical to p, without performing them.
+//
+// Entries that are missing from target are created, and entries that are not in p are deleted. Files that exist in
+// both places are overwritten if their sizes or SHA-256 digests differ. Symlinks are overwritten if their targets
+// differ, files and directories are overwritten if their permissions differ, and any entry is overwritten if its type
+// differs. Overwriting a file or directory whose contents already match only changes its permissions.
+func (p *Path) PlanSyncTo(target *Path) (plan SyncPlan, err error) {
+	if target.tree.sys == p.tree.sys && (target.path == p.path || p.isAncestorOf(target)) {
+		return nil, ErrInvalid
+	}
+	err = p.Walk(func(path *Path, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		dest := target.Join(p.nameOf(path))
+		action, differs, err := path.syncAction(dest, info)
+		if err == nil && differs {
+			plan = append(plan, SyncOp{action, path, dest})
+		}
+		return err
+	})
+	if err != nil || !target.Exists() {
+		return
+	}
+	err = target.Walk(func(path *Path, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		source := p.Join(target.nameOf(path))
+		sourceInfo, err := source.Stat()
+		if err != nil {
+			plan = append(plan, SyncOp{SyncDelete, nil, path})
+			return SkipDir
+		}
+		if info.IsDir() && !sourceInfo.IsDir() {
+			// Will be replaced entirely.
+			return SkipDir
+		}
+		return nil
+	})
+	return
+}
+
+func (p *Path) MustPlanSyncTo(target *Path) SyncPlan { return must1(p.PlanSyncTo(target)).(SyncPlan) }
+
+// SyncTo makes target identical to p, dispatching a CopyEvent, CopyOverEvent, DeleteFileEvent or DeleteDirEvent for
+// each entry it changes.
+func (p *Path) SyncTo(target *Path) error {
+	plan, err := p.PlanSyncTo(target)
+	if err != nil {
+		return err
+	}
+	return plan.Apply()
+}
+
+func (p *Path) MustSyncTo(target *Path) { must(p.SyncTo(target)) }
+
+// Apply performs the operations in the plan, in order.
+func (s SyncPlan) Apply() error {
+	var dirs []SyncOp
+	for _, op := range s {
+		if op.Action == SyncDelete {
+			if err := op.Target.DeleteIfExists(); err != nil {
+				return err
+			}
+			continue
+		}
+		info, err := op.Source.Stat()
+		if err != nil {
+			return err
+		}
+		mode := info.Mode()
+		switch {
+		case mode.IsDir():
+			dirs = append(dirs, op)
+			err = op.Source.copyDirTo(op.Target)
+		case mode&os.ModeSymlink != 0:
+			err = op.Source.copyLinkTo(op.Target)
+		case mode.IsRegular():
+			err = op.Source.copyFileTo(op.Target, mode.Perm())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		info, err := dirs[i].Source.Stat()
+		if err == nil {
+			err = dirs[i].Target.chmodUnlessEqual(info.Mode().Perm())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s SyncPlan) MustApply() { must(s.Apply()) }
+
+// syncAction determines whether target needs to be created or overwritten to match p.
+func (p *Path) syncAction(target *Path, info os.FileInfo) (action SyncAction, differs bool, err error) {
+	targetInfo, err := target.Stat()
+	if err != nil {
+		return SyncCreate, true, nil
+	}
+	mode, targetMode := info.Mode(), targetInfo.Mode()
+	if mode.Type() != targetMode.Type() {
+		return SyncOverwrite, true, nil
+	}
+	if mode&os.ModeSymlink == 0 && mode.Perm() != targetMode.Perm() {
+		return SyncOverwrite, true, nil
+	}
+	switch {
+	case mode&os.ModeSymlink != 0:
+		var linkTarget, currentTarget string
+		if linkTarget, err = p.tree.sys.Readlink(p.path); err != nil {
+			return
+		}
+		if currentTarget, err = target.tree.sys.Readlink(target.path); err != nil {
+			return
+		}
+		differs = linkTarget != currentTarget
+	case mode.IsRegular():
+		if info.Size() != targetInfo.Size() {
+			differs = true
+			break
+		}
+		var digest, targetDigest Digest
+		if digest, err = p.Sha256Digest(); err != nil {
+			return
+		}
+		if targetDigest, err = target.Sha256Digest(); err != nil {
+			return
+		}
+		differs = !digest.Equals(targetDigest)
+	}
+	return SyncOverwrite, differs, nil
+}
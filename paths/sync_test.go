@@ -0,0 +1,71 @@
+package paths_test
+
+import (
+	. "github.com/hx/golib/paths"
+	. "github.com/hx/golib/testing"
+	"os"
+	"testing"
+)
+
+func TestPath_SyncTo(t *testing.T) {
+	eachSystem(t, func(t *testing.T, _ *Tree, dir *Path) {
+		src := dir.Join("src")
+		src.Join("same").MustWriteString("same")
+		src.Join("changed").MustWriteString("new")
+		src.Join("resized").MustWriteString("longer")
+		src.Join("new", "file").MustWriteString("new")
+		src.Join("dir").MustWriteString("now a file")
+		src.Join("mode").MustWriteString("mode")
+		src.Join("mode").MustChmod(0600)
+		src.Join("private").MustMakeMode(0700)
+
+		dst := dir.Join("dst")
+		dst.Join("same").MustWriteString("same")
+		dst.Join("changed").MustWriteString("old")
+		dst.Join("resized").MustWriteString("short")
+		dst.Join("extra", "file").MustWriteString("extra")
+		dst.Join("dir", "file").MustWriteString("was a dir")
+		dst.Join("mode").MustWriteString("mode")
+		dst.Join("private").MustMakeMode(0755)
+
+		snapshot := func() (names []string) {
+			dst.MustWalk(func(path *Path, _ os.FileInfo, _ error) error {
+				names = append(names, relName(dst, path))
+				return nil
+			})
+			return
+		}
+		before := snapshot()
+
+		plan, err := src.PlanSyncTo(dst)
+		Ok(t, err)
+		var ops []string
+		for _, op := range plan {
+			ops = append(ops, op.Action.String()+" "+relName(dst, op.Target))
+		}
+		Equals(t, []string{
+			"overwrite changed",
+			"overwrite dir",
+			"overwrite mode",
+			"create new",
+			"create new/file",
+			"overwrite private",
+			"overwrite resized",
+			"delete extra",
+		}, ops)
+		Equals(t, before, snapshot())
+
+		Ok(t, plan.Apply())
+		Equals(t, "new", dst.Join("changed").MustReadString())
+		Equals(t, "now a file", dst.Join("dir").MustReadString())
+		Equals(t, "new", dst.Join("new", "file").MustReadString())
+		Equals(t, "longer", dst.Join("resized").MustReadString())
+		Equals(t, os.FileMode(0600), dst.Join("mode").MustStat().Mode().Perm())
+		Equals(t, os.FileMode(0700), dst.Join("private").MustStat().Mode().Perm())
+		Assert(t, !dst.Join("extra").Exists(), "extra should have been deleted")
+
+		plan, err = src.PlanSyncTo(dst)
+		Ok(t, err)
+		Equals(t, 0, len(plan))
+	})
+}
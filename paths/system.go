@@ -14,11 +14,14 @@ type File interface {
 
 type System interface {
 	Chmod(name string, mode os.FileMode) error
+	Chown(name string, uid, gid int) error
 	Chtimes(name string, atime time.Time, mtime time.Time) error
 	CurrentUser() (*user.User, error)
 	Getwd() (dir string, err error)
 	Glob(pattern string) (matches []string, err error)
 	Join(elem ...string) string
+	Lchown(name string, uid, gid int) error
+	Link(oldname, newname string) error
 	Lstat(name string) (os.FileInfo, error)
 	MkdirAll(path string, perm os.FileMode) error
 	OpenFile(name string, flag int, perm os.FileMode) (File, error)
@@ -29,6 +32,7 @@ type System interface {
 	RemoveAll(path string) error
 	Rename(oldpath, newpath string) error
 	Root() string
+	Stat(name string) (os.FileInfo, error)
 	SupportsSymlinks() bool
 	Symlink(oldname, newname string) error
 }
@@ -0,0 +1,165 @@
+package paths
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"strings"
+)
+
+// tempDirSystem is implemented by systems that have a preferred location for temporary files, such as LocalSystem.
+type tempDirSystem interface{ TempDir() string }
+
+func (l local) TempDir() string { return os.TempDir() }
+
+// TempRoot returns the directory in which TempDir and TempFile create entries. For LocalSystem, this is os.TempDir.
+// For other systems, it's the value of the TMPDIR environment variable if the system has one, and a directory named
+// "tmp" in the root otherwise.
+func (t *Tree) TempRoot() *Path {
+	if sys, ok := t.sys.(tempDirSystem); ok {
+		return t.Join(sys.TempDir())
+	}
+	if dir := t.Getenv("TMPDIR"); dir != "" {
+		return t.Join(dir)
+	}
+	return t.Join("tmp")
+}
+
+// TempDir creates a new directory in TempRoot, and registers it for removal by CleanupTemp. The name of the directory
+// is pattern, with its last "*" replaced by a random string, or with a random string appended if it has no "*".
+func (t *Tree) TempDir(pattern string) (dir *Path, err error) {
+	root := t.TempRoot()
+	if err = root.Make(); err != nil {
+		return
+	}
+	for {
+		if dir, err = root.tempChild(pattern); err != nil {
+			return
+		}
+		if dir.Exists() {
+			continue
+		}
+		if err = dir.MakeMode(0700); err != nil {
+			return nil, err
+		}
+		t.registerTemp(dir)
+		return
+	}
+}
+func (t *Tree) MustTempDir(pattern string) *Path { return must1(t.TempDir(pattern)).(*Path) }
+
+// TempFile creates a new empty file in TempRoot, and registers it for removal by CleanupTemp. The file is named using
+// pattern in the same way as TempDir.
+func (t *Tree) TempFile(pattern string) (file *Path, err error) {
+	root := t.TempRoot()
+	if err = root.Make(); err != nil {
+		return
+	}
+	for {
+		if file, err = root.tempChild(pattern); err != nil {
+			return
+		}
+		var f File
+		f, err = t.sys.OpenFile(file.path, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0600)
+		if err != nil {
+			if file.Exists() {
+				continue
+			}
+			return nil, err
+		}
+		if err = f.Close(); err != nil {
+			return nil, err
+		}
+		t.registerTemp(file)
+		return
+	}
+}
+func (t *Tree) MustTempFile(pattern string) *Path { return must1(t.TempFile(pattern)).(*Path) }
+
+// TB is the subset of testing.TB used by TestTempDir and TestTempFile, so that they don't make every program that uses
+// this package import the testing package.
+type TB interface {
+	Helper()
+	Fatal(args ...interface{})
+	Cleanup(func())
+}
+
+// TestTempDir is like MustTempDir, but fails tb instead of panicking, and removes the directory when tb's cleanup
+// functions are run.
+func (t *Tree) TestTempDir(tb TB, pattern string) *Path {
+	tb.Helper()
+	dir, err := t.TempDir(pattern)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { t.cleanupTemp(dir) })
+	return dir
+}
+
+// TestTempFile is like MustTempFile, but fails tb instead of panicking, and removes the file when tb's cleanup
+// functions are run.
+func (t *Tree) TestTempFile(tb TB, pattern string) *Path {
+	tb.Helper()
+	file, err := t.TempFile(pattern)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { t.cleanupTemp(file) })
+	return file
+}
+
+// CleanupTemp removes every temporary directory and file created by the tree that hasn't already been removed, most
+// recent first. If any can't be removed, the first error encountered is returned, and they remain registered.
+func (t *Tree) CleanupTemp() (err error) {
+	t.tempMutex.Lock()
+	temps := t.temps
+	t.temps = nil
+	t.tempMutex.Unlock()
+	var remaining []*Path
+	for i := len(temps) - 1; i >= 0; i-- {
+		if e := temps[i].DeleteIfExists(); e != nil {
+			remaining = append([]*Path{temps[i]}, remaining...)
+			if err == nil {
+				err = e
+			}
+		}
+	}
+	if remaining != nil {
+		t.tempMutex.Lock()
+		t.temps = append(remaining, t.temps...)
+		t.tempMutex.Unlock()
+	}
+	return
+}
+func (t *Tree) MustCleanupTemp() { must(t.CleanupTemp()) }
+
+func (t *Tree) registerTemp(p *Path) {
+	t.tempMutex.Lock()
+	defer t.tempMutex.Unlock()
+	t.temps = append(t.temps, p)
+}
+
+// cleanupTemp removes p and unregisters it.
+func (t *Tree) cleanupTemp(p *Path) {
+	t.tempMutex.Lock()
+	for i, temp := range t.temps {
+		if temp == p {
+			t.temps = append(t.temps[:i:i], t.temps[i+1:]...)
+			break
+		}
+	}
+	t.tempMutex.Unlock()
+	_ = p.DeleteIfExists()
+}
+
+func (p *Path) tempChild(pattern string) (*Path, error) {
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	random := hex.EncodeToString(suffix)
+	if i := strings.LastIndex(pattern, "*"); i >= 0 {
+		return p.Join(pattern[:i] + random + pattern[i+1:]), nil
+	}
+	return p.Join(pattern + random), nil
+}
@@ -0,0 +1,42 @@
+package paths_test
+
+import (
+	. "github.com/hx/golib/paths"
+	. "github.com/hx/golib/testing"
+	"strings"
+	"testing"
+)
+
+func TestTree_TempDir(t *testing.T) {
+	for _, tree := range []*Tree{NewTree(), NewTreeWithSystem(NewVirtualSystem())} {
+		dir := tree.MustTempDir("work-*.d")
+		Assert(t, dir.IsDir(), "temp dir should exist")
+		Equals(t, tree.TempRoot().String(), dir.Parent().String())
+		Assert(t, strings.HasPrefix(dir.Base(), "work-"), "temp dir should start with the pattern prefix")
+		Assert(t, strings.HasSuffix(dir.Base(), ".d"), "temp dir should end with the pattern suffix")
+
+		file := tree.MustTempFile("scratch")
+		Assert(t, file.IsNonDir(), "temp file should exist")
+		Equals(t, int64(0), file.MustSize())
+		Assert(t, file.Base() != tree.MustTempFile("scratch").Base(), "temp names should be unique")
+
+		dir.Join("child").MustWriteString("hello")
+		Ok(t, tree.CleanupTemp())
+		Equals(t, false, dir.Exists())
+		Equals(t, false, file.Exists())
+		Ok(t, tree.CleanupTemp())
+	}
+}
+
+func TestTree_TestTempDir(t *testing.T) {
+	tree := NewTreeWithSystem(NewVirtualSystem())
+	var dir, file *Path
+	t.Run("sub", func(t *testing.T) {
+		dir = tree.TestTempDir(t, "dir")
+		file = tree.TestTempFile(t, "file")
+		dir.Join("child").MustWriteString("hello")
+		Equals(t, 1, len(dir.MustChildren()))
+	})
+	Equals(t, false, dir.Exists())
+	Equals(t, false, file.Exists())
+}
@@ -1,11 +1,21 @@
 package paths
 
-import "sync/atomic"
+import (
+	"sync"
+	"sync/atomic"
+)
 
 type Tree struct {
 	*Path
 	sys       System
 	listeners map[uint64]Listener
+	queue     chan func()
+	done      chan struct{}
+	temps     []*Path
+
+	listenersMutex sync.Mutex
+	queueMutex     sync.RWMutex
+	tempMutex      sync.Mutex
 }
 
 func NewTree() (t *Tree) { return NewTreeWithSystem(LocalSystem) }
@@ -22,14 +32,91 @@ func NewTreeWithSystem(system System) (t *Tree) {
 
 var nextID = new(uint64)
 
+// Subscribe adds a listener that will be called for each event dispatched by the tree. It is safe to call from
+// multiple goroutines, and from within listeners.
 func (t *Tree) Subscribe(listener Listener) (unsubscribe func()) {
 	id := atomic.AddUint64(nextID, 1)
+	t.listenersMutex.Lock()
 	t.listeners[id] = listener
-	return func() { delete(t.listeners, id) }
+	t.listenersMutex.Unlock()
+	return func() {
+		t.listenersMutex.Lock()
+		delete(t.listeners, id)
+		t.listenersMutex.Unlock()
+	}
+}
+
+// DispatchAsync makes the tree deliver events to listeners from a separate goroutine, so that slow listeners don't
+// hold up file system operations. Events are queued in a buffer of the given size, and delivered in the order they
+// were dispatched. Once the buffer is full, operations that dispatch events will block until there is room.
+//
+// Listeners must not call Flush or DispatchSync, and should avoid dispatching events themselves, as doing so can
+// deadlock once the buffer is full. Calling DispatchAsync when already in asynchronous mode has no effect.
+func (t *Tree) DispatchAsync(bufferSize int) *Tree {
+	t.queueMutex.Lock()
+	defer t.queueMutex.Unlock()
+	if t.queue != nil {
+		return t
+	}
+	queue, done := make(chan func(), bufferSize), make(chan struct{})
+	t.queue, t.done = queue, done
+	go func() {
+		for fn := range queue {
+			fn()
+		}
+		close(done)
+	}()
+	return t
+}
+
+// DispatchSync waits for any queued events to be delivered, and then returns the tree to synchronous dispatch, where
+// events are delivered to listeners before the operations that cause them return. This is the default mode.
+func (t *Tree) DispatchSync() {
+	t.queueMutex.Lock()
+	queue, done := t.queue, t.done
+	t.queue, t.done = nil, nil
+	t.queueMutex.Unlock()
+	if queue != nil {
+		close(queue)
+		<-done
+	}
+}
+
+// Flush blocks until every event dispatched before it was called has been delivered to listeners. It has no effect in
+// synchronous mode.
+func (t *Tree) Flush() {
+	done := make(chan struct{})
+	if t.enqueue(func() { close(done) }) {
+		<-done
+	}
 }
 
 func (t *Tree) dispatch(event Event) {
+	if !t.enqueue(func() { t.deliver(event) }) {
+		t.deliver(event)
+	}
+}
+
+// enqueue adds fn to the asynchronous dispatch queue, and returns true, or returns false if the tree is in synchronous
+// mode.
+func (t *Tree) enqueue(fn func()) bool {
+	t.queueMutex.RLock()
+	defer t.queueMutex.RUnlock()
+	if t.queue == nil {
+		return false
+	}
+	t.queue <- fn
+	return true
+}
+
+func (t *Tree) deliver(event Event) {
+	t.listenersMutex.Lock()
+	listeners := make([]Listener, 0, len(t.listeners))
 	for _, l := range t.listeners {
+		listeners = append(listeners, l)
+	}
+	t.listenersMutex.Unlock()
+	for _, l := range listeners {
 		l(event)
 	}
 }
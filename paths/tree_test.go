@@ -0,0 +1,59 @@
+package paths_test
+
+import (
+	"fmt"
+	. "github.com/hx/golib/paths"
+	. "github.com/hx/golib/testing"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTree_Subscribe_Concurrent(t *testing.T) {
+	tree := NewTree()
+	dir := tree.Join(t.TempDir())
+	var count int64
+	defer tree.Subscribe(func(Event) { atomic.AddInt64(&count, 1) })()
+
+	var wait sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			unsubscribe := tree.Subscribe(func(Event) {})
+			dir.Join(fmt.Sprint(i)).MustWriteString("hello")
+			unsubscribe()
+		}(i)
+	}
+	wait.Wait()
+	Equals(t, int64(10), atomic.LoadInt64(&count))
+}
+
+func TestTree_DispatchAsync(t *testing.T) {
+	tree := NewTree().DispatchAsync(2)
+	dir := tree.Join(t.TempDir())
+
+	var (
+		names   []string
+		release = make(chan struct{})
+	)
+	defer tree.Subscribe(func(event Event) {
+		<-release
+		names = append(names, event.Path().Base())
+	})()
+
+	// These would block if dispatch were synchronous, since the listener is waiting to be released.
+	dir.Join("a").MustWriteString("a")
+	dir.Join("b").MustWriteString("b")
+	close(release)
+
+	tree.Flush()
+	Equals(t, []string{"a", "b"}, names)
+
+	dir.Join("c").MustWriteString("c")
+	tree.DispatchSync()
+	Equals(t, []string{"a", "b", "c"}, names)
+
+	dir.Join("d").MustWriteString("d")
+	Equals(t, []string{"a", "b", "c", "d"}, names)
+}
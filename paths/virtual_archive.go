@@ -0,0 +1,48 @@
+package paths
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// LoadDir copies the contents of dir on the local file system into the root of v, preserving modes, modification times
+// and symlinks.
+func (v *VirtualSystem) LoadDir(dir string) error {
+	return NewTreeWithSystem(v).extract(NewTree().Join(dir).archiveSource())
+}
+
+// LoadTar extracts a tar archive into the root of v, preserving modes, modification times and symlinks. If the archive
+// is gzipped, it is decompressed automatically. Entries other than files, directories and symlinks are ignored.
+func (v *VirtualSystem) LoadTar(reader io.Reader) error {
+	return NewTreeWithSystem(v).extract(tarSource(reader))
+}
+
+// LoadZip extracts a zip archive into the root of v, preserving modes, modification times and symlinks.
+func (v *VirtualSystem) LoadZip(reader io.ReaderAt, size int64) error {
+	return NewTreeWithSystem(v).extract(zipSource(reader, size))
+}
+
+// DumpDir copies the contents of v into dir on the local file system, preserving modes, modification times and
+// symlinks.
+func (v *VirtualSystem) DumpDir(dir string) error {
+	return NewTree().Join(dir).extract(NewTreeWithSystem(v).archiveSource())
+}
+
+// DumpTar writes the contents of v to writer as a tar archive.
+func (v *VirtualSystem) DumpTar(writer io.Writer) error {
+	return writeTar(writer, NewTreeWithSystem(v).archiveSource())
+}
+
+// DumpTarGz writes the contents of v to writer as a gzipped tar archive.
+func (v *VirtualSystem) DumpTarGz(writer io.Writer) (err error) {
+	gz := gzip.NewWriter(writer)
+	if err = v.DumpTar(gz); err == nil {
+		err = gz.Close()
+	}
+	return
+}
+
+// DumpZip writes the contents of v to writer as a zip archive.
+func (v *VirtualSystem) DumpZip(writer io.Writer) error {
+	return writeZip(writer, NewTreeWithSystem(v).archiveSource())
+}
@@ -0,0 +1,79 @@
+package paths_test
+
+import (
+	"archive/tar"
+	"bytes"
+	. "github.com/hx/golib/paths"
+	. "github.com/hx/golib/testing"
+	"os"
+	"testing"
+	"time"
+)
+
+func archiveFixture(t *testing.T) *VirtualSystem {
+	sys := NewVirtualSystem()
+	tree := NewTreeWithSystem(sys)
+	modified := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	tree.Join("dir", "file").MustWriteString("contents")
+	tree.Join("dir", "file").MustChmod(0640)
+	tree.Join("dir", "empty").MustMakeMode(0700)
+	if LocalSystem.SupportsSymlinks() {
+		tree.Join("dir", "file").MustSymlinkTo(tree.Join("link"))
+	}
+	for _, p := range []*Path{tree.Join("dir", "file"), tree.Join("dir", "empty"), tree.Join("dir")} {
+		Ok(t, sys.Chtimes(p.String(), modified, modified))
+	}
+	return sys
+}
+
+func assertArchiveFixture(t *testing.T, sys *VirtualSystem) {
+	t.Helper()
+	diff := archiveFixture(t).Diff(sys)
+	Assert(t, diff.IsEmpty(), "diff should be empty: %v", diff)
+	tree := NewTreeWithSystem(sys)
+	modified := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, p := range []*Path{tree.Join("dir", "file"), tree.Join("dir", "empty"), tree.Join("dir")} {
+		Assert(t, p.MustStat().ModTime().Equal(modified), "%s should have been modified at %s", p, modified)
+	}
+}
+
+func TestVirtualSystem_Tar(t *testing.T) {
+	for _, gz := range []bool{false, true} {
+		buf := new(bytes.Buffer)
+		if gz {
+			Ok(t, archiveFixture(t).DumpTarGz(buf))
+		} else {
+			Ok(t, archiveFixture(t).DumpTar(buf))
+		}
+		sys := NewVirtualSystem()
+		Ok(t, sys.LoadTar(buf))
+		assertArchiveFixture(t, sys)
+	}
+}
+
+func TestVirtualSystem_Zip(t *testing.T) {
+	buf := new(bytes.Buffer)
+	Ok(t, archiveFixture(t).DumpZip(buf))
+	sys := NewVirtualSystem()
+	Ok(t, sys.LoadZip(bytes.NewReader(buf.Bytes()), int64(buf.Len())))
+	assertArchiveFixture(t, sys)
+}
+
+func TestVirtualSystem_Dir(t *testing.T) {
+	dir := t.TempDir()
+	Ok(t, archiveFixture(t).DumpDir(dir))
+	stat, err := os.Stat(dir + string(os.PathSeparator) + "dir")
+	Ok(t, err)
+	Equals(t, os.FileMode(0755), stat.Mode().Perm())
+	sys := NewVirtualSystem()
+	Ok(t, sys.LoadDir(dir))
+	assertArchiveFixture(t, sys)
+}
+
+func TestVirtualSystem_LoadTar_Escape(t *testing.T) {
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	Ok(t, tw.WriteHeader(&tar.Header{Name: "../escape", Typeflag: tar.TypeReg, Mode: 0644}))
+	Ok(t, tw.Close())
+	Equals(t, ErrInvalid, NewVirtualSystem().LoadTar(buf))
+}
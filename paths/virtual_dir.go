@@ -6,7 +6,6 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
-	"time"
 )
 
 type virtualDir struct {
@@ -15,13 +14,8 @@ type virtualDir struct {
 }
 
 func newVirtualDir() *virtualDir {
-	now := time.Now()
 	return &virtualDir{
-		virtualEntryBase: &virtualEntryBase{
-			mode:     0744 | fs.ModeDir,
-			accessed: now,
-			modified: now,
-		},
+		virtualEntryBase: &virtualEntryBase{virtualInode: newVirtualInode(0755 | fs.ModeDir)},
 	}
 }
 
@@ -2,6 +2,7 @@ package paths
 
 import (
 	"io/fs"
+	"sync/atomic"
 	"time"
 )
 
@@ -11,12 +12,63 @@ type virtualEntry interface {
 	entry() *virtualEntryBase
 }
 
+// virtualEntryBase holds the name and parent of an entry. Everything else about the entry is held by its inode, which
+// hard links share.
 type virtualEntryBase struct {
-	name     string
+	name   string
+	parent *virtualDir
+	*virtualInode
+}
+
+type virtualInode struct {
+	ino      uint64
+	links    int // Files and symlinks only
 	mode     fs.FileMode
 	accessed time.Time
 	modified time.Time
-	parent   *virtualDir
+	uid      int
+	gid      int
+	contents []byte // Files only
+}
+
+// lastVirtualIno is the most recently allocated inode number. Numbers are unique across all virtual systems.
+var lastVirtualIno uint64
+
+func newVirtualInode(mode fs.FileMode) *virtualInode {
+	now := time.Now()
+	return &virtualInode{
+		ino:      atomic.AddUint64(&lastVirtualIno, 1),
+		links:    1,
+		mode:     mode,
+		accessed: now,
+		modified: now,
+	}
+}
+
+// linkCount returns the number of hard links to e. As on Unix, directories are linked to from their parents, from
+// themselves, and from each of their subdirectories.
+func linkCount(e virtualEntry) uint64 {
+	dir, isDir := e.(*virtualDir)
+	if !isDir {
+		return uint64(e.entry().links)
+	}
+	count := uint64(2)
+	for _, child := range dir.children {
+		if _, isDir = child.(*virtualDir); isDir {
+			count++
+		}
+	}
+	return count
+}
+
+// unlink decrements the link counts of e and everything beneath it, which are being removed.
+func unlink(e virtualEntry) {
+	e.entry().links--
+	if dir, isDir := e.(*virtualDir); isDir {
+		for _, child := range dir.children {
+			unlink(child)
+		}
+	}
 }
 
 func (e *virtualEntryBase) entry() *virtualEntryBase { return e }
@@ -25,3 +77,42 @@ func (e *virtualEntryBase) Type() fs.FileMode        { return e.mode }
 func (e *virtualEntryBase) Mode() fs.FileMode        { return e.mode }
 func (e *virtualEntryBase) ModTime() time.Time       { return e.modified }
 func (e *virtualEntryBase) Sys() interface{}         { return nil }
+
+// virtualInfo is a snapshot of a virtualEntry, safe to use after the entry has been changed.
+type virtualInfo struct {
+	name     string
+	size     int64
+	mode     fs.FileMode
+	modified time.Time
+	uid      int
+	gid      int
+	metadata fileMetadata
+}
+
+func newVirtualInfo(e virtualEntry) *virtualInfo {
+	base := e.entry()
+	return &virtualInfo{
+		name:     base.name,
+		size:     e.Size(),
+		mode:     base.mode,
+		modified: base.modified,
+		uid:      base.uid,
+		gid:      base.gid,
+		metadata: fileMetadata{
+			accessed: base.accessed,
+			links:    linkCount(e),
+			id:       FileID{Inode: base.ino},
+		},
+	}
+}
+
+func (i *virtualInfo) Name() string               { return i.name }
+func (i *virtualInfo) Size() int64                { return i.size }
+func (i *virtualInfo) Mode() fs.FileMode          { return i.mode }
+func (i *virtualInfo) Type() fs.FileMode          { return i.mode.Type() }
+func (i *virtualInfo) ModTime() time.Time         { return i.modified }
+func (i *virtualInfo) IsDir() bool                { return i.mode.IsDir() }
+func (i *virtualInfo) Sys() interface{}           { return nil }
+func (i *virtualInfo) Info() (fs.FileInfo, error) { return i, nil }
+func (i *virtualInfo) owner() (uid, gid int)      { return i.uid, i.gid }
+func (i *virtualInfo) fileMetadata() fileMetadata { return i.metadata }
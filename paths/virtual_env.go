@@ -0,0 +1,101 @@
+package paths
+
+import (
+	"path/filepath"
+	"sort"
+)
+
+// Getwd returns the working directory set by Chdir, or the root if none has been set.
+func (v *VirtualSystem) Getwd() (dir string, err error) {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	if v.wd == "" {
+		return v.rootPath, nil
+	}
+	return v.wd, nil
+}
+
+// Chdir sets the working directory returned by Getwd, against which relative names given to other methods are
+// resolved. A relative dir is resolved against the current working directory. The directory must exist, and, if
+// EnforcePermissions is on, the current user must be able to search it.
+func (v *VirtualSystem) Chdir(dir string) error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	dir = filepath.Clean(v.abs(dir))
+	if err := v.traverse(dir); err != nil {
+		return pathError("chdir", dir, err)
+	}
+	entry := v.resolve(dir)
+	if link, ok := entry.(*virtualSymlink); ok {
+		entry = link.resolveRecursive()
+	}
+	switch entry.(type) {
+	case nil:
+		return pathError("chdir", dir, ErrPathNotFound)
+	case *virtualDir:
+	default:
+		return pathError("chdir", dir, ErrNonDirectory)
+	}
+	if err := v.check(entry, permExecute); err != nil {
+		return pathError("chdir", dir, err)
+	}
+	v.wd = dir
+	return nil
+}
+
+// Setenv sets the value of an environment variable. VirtualSystem environment variables are independent of those of
+// the current process.
+func (v *VirtualSystem) Setenv(key, value string) *VirtualSystem {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if v.env == nil {
+		v.env = make(map[string]string)
+	}
+	v.env[key] = value
+	return v
+}
+
+// Unsetenv removes an environment variable.
+func (v *VirtualSystem) Unsetenv(key string) *VirtualSystem {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	delete(v.env, key)
+	return v
+}
+
+// LookupEnv returns the value of an environment variable, and whether it's set.
+func (v *VirtualSystem) LookupEnv(key string) (value string, ok bool) {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	value, ok = v.env[key]
+	return
+}
+
+// Getenv returns the value of an environment variable, or an empty string if it's not set.
+func (v *VirtualSystem) Getenv(key string) string {
+	value, _ := v.LookupEnv(key)
+	return value
+}
+
+// Environ returns every environment variable in the form "key=value", in sorted order.
+func (v *VirtualSystem) Environ() []string {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	environ := make([]string, 0, len(v.env))
+	for key, value := range v.env {
+		environ = append(environ, key+"="+value)
+	}
+	sort.Strings(environ)
+	return environ
+}
+
+func copyEnv(env map[string]string) map[string]string {
+	if env == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(env))
+	for key, value := range env {
+		copied[key] = value
+	}
+	return copied
+}
@@ -0,0 +1,61 @@
+package paths_test
+
+import (
+	"errors"
+	. "github.com/hx/golib/paths"
+	. "github.com/hx/golib/testing"
+	"io/fs"
+	"os/user"
+	"testing"
+)
+
+func TestVirtualSystem_Chdir(t *testing.T) {
+	sys := NewVirtualSystem()
+	tree := NewTreeWithSystem(sys)
+	Equals(t, tree.String(), tree.MustWd().String())
+
+	home := tree.Join("home", "alice")
+	project := home.Join("project", "sub").MustMake()
+	home.Join("file").MustWriteString("file")
+	sys.SetUser(&user.User{Uid: "1000", Gid: "1000", Username: "alice", HomeDir: home.String()})
+	Equals(t, home.String(), tree.MustUserHome().String())
+
+	Ok(t, sys.Chdir(project.String()))
+	Equals(t, project.String(), tree.MustWd().String())
+	Ok(t, sys.Chdir(".."))
+	Equals(t, project.Parent().String(), tree.MustWd().String())
+
+	// Relative names are resolved against the working directory.
+	info, err := sys.Stat("sub")
+	Ok(t, err)
+	Equals(t, true, info.IsDir())
+	contents, err := sys.ReadFile(reslash("../file"))
+	Ok(t, err)
+	Equals(t, "file", string(contents))
+	Ok(t, sys.MkdirAll(reslash("new/dir"), 0755))
+	Equals(t, true, project.Parent().Join("new", "dir").IsDir())
+	Ok(t, sys.Remove("new"))
+	Equals(t, false, project.Parent().Join("new").Exists())
+
+	err = sys.Chdir("missing")
+	Assert(t, errors.Is(err, fs.ErrNotExist), "expected fs.ErrNotExist, got %v", err)
+	Assert(t, sys.Chdir(home.Join("file").String()) != nil, "can't change to a file")
+	Equals(t, project.Parent().String(), NewTreeWithSystem(sys.Clone()).MustWd().String())
+}
+
+func TestVirtualSystem_Environment(t *testing.T) {
+	sys := NewVirtualSystem()
+	tree := NewTreeWithSystem(sys)
+	home, scratch := tree.Join("home", "alice").String(), tree.Join("scratch").String()
+	sys.Setenv("HOME", home).Setenv("TMPDIR", scratch)
+	Equals(t, scratch, sys.Getenv("TMPDIR"))
+	Equals(t, []string{"HOME=" + home, "TMPDIR=" + scratch}, sys.Environ())
+	Equals(t, scratch, tree.TempRoot().String())
+	Equals(t, tree.Join("home", "alice", "bin").String(), tree.ExpandEnv(reslash("$HOME/bin")).String())
+
+	sys.Unsetenv("TMPDIR")
+	_, ok := tree.LookupEnv("TMPDIR")
+	Equals(t, false, ok)
+	Equals(t, tree.Join("tmp").String(), tree.TempRoot().String())
+	Equals(t, home, sys.Clone().Getenv("HOME"))
+}
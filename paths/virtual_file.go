@@ -10,18 +10,14 @@ import (
 
 type virtualFile struct {
 	*virtualEntryBase
-	contents []byte
 }
 
 func newVirtualFile(name string, parent *virtualDir, perm os.FileMode) *virtualFile {
-	now := time.Now()
 	return &virtualFile{
 		virtualEntryBase: &virtualEntryBase{
-			name:     name,
-			mode:     perm.Perm(),
-			accessed: now,
-			modified: now,
-			parent:   parent,
+			name:         name,
+			parent:       parent,
+			virtualInode: newVirtualInode(perm.Perm()),
 		},
 	}
 }
@@ -31,11 +27,14 @@ func (f *virtualFile) IsDir() bool                { return false }
 func (f *virtualFile) Info() (fs.FileInfo, error) { return f, nil }
 
 type virtualOpenFile struct {
+	sys    *VirtualSystem
 	file   *virtualFile
 	offset int
 }
 
 func (f *virtualOpenFile) Read(p []byte) (n int, err error) {
+	f.sys.mutex.Lock()
+	defer f.sys.mutex.Unlock()
 	if f.offset == len(f.file.contents) {
 		return 0, io.EOF
 	}
@@ -51,6 +50,8 @@ func (f *virtualOpenFile) Read(p []byte) (n int, err error) {
 func (f *virtualOpenFile) Close() error { return nil }
 
 func (f *virtualOpenFile) Write(p []byte) (n int, err error) {
+	f.sys.mutex.Lock()
+	defer f.sys.mutex.Unlock()
 	if len(p) == 0 {
 		return
 	}
@@ -69,6 +70,8 @@ func (f *virtualOpenFile) Write(p []byte) (n int, err error) {
 }
 
 func (f *virtualOpenFile) Seek(offset int64, whence int) (int64, error) {
+	f.sys.mutex.Lock()
+	defer f.sys.mutex.Unlock()
 	length := len(f.file.contents)
 	switch whence {
 	case 0:
@@ -76,7 +79,7 @@ func (f *virtualOpenFile) Seek(offset int64, whence int) (int64, error) {
 	case 1:
 		f.offset += int(offset)
 	case 2:
-		f.offset = length - int(offset)
+		f.offset = length + int(offset)
 	default:
 		return 0, fmt.Errorf("%d is not valid for argument whence", whence)
 	}
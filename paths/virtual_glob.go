@@ -0,0 +1,56 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// glob appends to matches the names of entries in dir that match pattern. It mirrors the unexported function of the
+// same name in path/filepath, so that results are identical to those of filepath.Glob.
+func (v *VirtualSystem) glob(dir, pattern string, matches []string) ([]string, error) {
+	entry := v.resolve(dir)
+	if link, ok := entry.(*virtualSymlink); ok {
+		entry = link.resolveRecursive()
+	}
+	d, isDir := entry.(*virtualDir)
+	if !isDir {
+		return matches, nil
+	}
+	names := make([]string, len(d.children))
+	for i, child := range d.children {
+		names[i] = child.entry().name
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		matched, err := filepath.Match(pattern, name)
+		if err != nil {
+			return matches, err
+		}
+		if matched {
+			matches = append(matches, filepath.Join(dir, name))
+		}
+	}
+	return matches, nil
+}
+
+func globHasMeta(path string) bool {
+	magicChars := `*?[`
+	if runtime.GOOS != "windows" {
+		magicChars = `*?[\`
+	}
+	return strings.ContainsAny(path, magicChars)
+}
+
+func globCleanPath(path string) string {
+	vol := filepath.VolumeName(path)
+	switch path[len(vol):] {
+	case "":
+		return path + "."
+	case string(os.PathSeparator):
+		return path
+	}
+	return path[:len(path)-1]
+}
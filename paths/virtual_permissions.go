@@ -0,0 +1,139 @@
+package paths
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+)
+
+const (
+	permExecute os.FileMode = 1 << iota
+	permWrite
+	permRead
+)
+
+// EnforcePermissions turns the enforcement of Unix permissions on or off. It's off by default.
+//
+// When it's on, operations fail with ErrPermission unless the user returned by CurrentUser is allowed to perform them.
+// Reading files and listing directories need read permission, writing files needs write permission, searching
+// directories needs execute permission, and creating, removing and renaming entries needs write and execute permission
+// on their parent directories. Only the owner of an entry can change its mode or times, and only root can give it
+// away. As on Unix, root is allowed to do anything.
+//
+// Files that are already open aren't affected by later changes to their permissions. Glob doesn't check permissions.
+func (v *VirtualSystem) EnforcePermissions(enforce bool) *VirtualSystem {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.enforce = enforce
+	return v
+}
+
+// SetUser sets the user returned by CurrentUser, who owns new entries, and whose permissions are checked when
+// EnforcePermissions is on. Users whose Uid and Gid aren't numbers are treated as belonging to nobody.
+func (v *VirtualSystem) SetUser(u *user.User) *VirtualSystem {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	copied := *u
+	v.user = &copied
+	v.uid, v.gid = -1, -1
+	if uid, err := strconv.Atoi(u.Uid); err == nil {
+		v.uid = uid
+	}
+	if gid, err := strconv.Atoi(u.Gid); err == nil {
+		v.gid = gid
+	}
+	return v
+}
+
+func (v *VirtualSystem) enforcing() bool { return v.enforce && v.uid != 0 }
+
+// permitted reports whether the current user has all the permissions in want on entry.
+func (v *VirtualSystem) permitted(entry virtualEntry, want os.FileMode) bool {
+	if !v.enforcing() {
+		return true
+	}
+	base := entry.entry()
+	perm := base.mode.Perm()
+	switch {
+	case base.uid == v.uid:
+		perm >>= 6
+	case base.gid == v.gid:
+		perm >>= 3
+	}
+	return perm&want == want
+}
+
+// check returns ErrPermission unless the current user has all the permissions in want on entry. It's satisfied if
+// entry is nil, so that the operation can report that the entry doesn't exist.
+func (v *VirtualSystem) check(entry virtualEntry, want os.FileMode) error {
+	if entry == nil || v.permitted(entry, want) {
+		return nil
+	}
+	return ErrPermission
+}
+
+// checkOwner returns ErrPermission unless the current user owns entry.
+func (v *VirtualSystem) checkOwner(entry virtualEntry) error {
+	if entry == nil || !v.enforcing() || entry.entry().uid == v.uid {
+		return nil
+	}
+	return ErrPermission
+}
+
+// checkParent returns ErrPermission unless the current user can create and remove entries in the parent directory of
+// name.
+func (v *VirtualSystem) checkParent(name string) error {
+	parent := v.resolve(filepath.Dir(name))
+	if link, ok := parent.(*virtualSymlink); ok {
+		parent = link.resolveRecursive()
+	}
+	return v.check(parent, permWrite|permExecute)
+}
+
+// checkRemove returns ErrPermission unless the current user can remove entry, and, if it's a directory, everything
+// beneath it.
+func (v *VirtualSystem) checkRemove(entry virtualEntry) error {
+	dir, isDir := entry.(*virtualDir)
+	if !isDir || len(dir.children) == 0 {
+		return nil
+	}
+	if err := v.check(dir, permRead|permWrite|permExecute); err != nil {
+		return err
+	}
+	for _, child := range dir.children {
+		if err := v.checkRemove(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// traverse returns ErrPermission unless the current user can search every directory leading to name.
+func (v *VirtualSystem) traverse(name string) error {
+	if !v.enforcing() {
+		return nil
+	}
+	dir := v.rootDir
+	for _, part := range segments(filepath.Dir(v.abs(name))) {
+		if !v.permitted(dir, permExecute) {
+			return ErrPermission
+		}
+		entry := dir.resolve(part)
+		if link, ok := entry.(*virtualSymlink); ok {
+			entry = link.resolveRecursive()
+		}
+		next, isDir := entry.(*virtualDir)
+		if !isDir {
+			return nil
+		}
+		dir = next
+	}
+	return v.check(dir, permExecute)
+}
+
+// own makes the current user the owner of entry, which is being created.
+func (v *VirtualSystem) own(entry virtualEntry) {
+	base := entry.entry()
+	base.uid, base.gid = v.uid, v.gid
+}
@@ -0,0 +1,63 @@
+package paths_test
+
+import (
+	"errors"
+	. "github.com/hx/golib/paths"
+	. "github.com/hx/golib/testing"
+	"io/fs"
+	"os/user"
+	"testing"
+)
+
+func TestVirtualSystem_EnforcePermissions(t *testing.T) {
+	sys := NewVirtualSystem()
+	tree := NewTreeWithSystem(sys)
+	shared := tree.Join("shared")
+	shared.MustWriteString("shared")
+	output := tree.Join("output").MustMakeMode(0555)
+	private := tree.Join("private").MustMakeMode(0700)
+	private.Join("secret").MustWriteString("secret")
+	home := tree.Join("home").MustMake()
+	home.MustChown(1000, 1000)
+	full := home.Join("full")
+	full.Join("inner").MustWriteString("inner")
+	full.MustChmod(0555)
+
+	// Like a real root directory, the root of a VirtualSystem can be searched by everyone.
+	Equals(t, fs.FileMode(0755), tree.MustStat().Mode().Perm())
+
+	sys.SetUser(&user.User{Uid: "1000", Gid: "1000", Username: "alice"}).EnforcePermissions(true)
+	denied := func(err error) {
+		t.Helper()
+		Assert(t, errors.Is(err, fs.ErrPermission), "expected permission to be denied, got %v", err)
+	}
+
+	Equals(t, "shared", shared.MustReadString())
+	denied(shared.WriteString("changed"))
+	denied(shared.Delete())
+	denied(output.Join("file").WriteString("output"))
+	denied(output.Join("dir").Make())
+	_, err := private.Join("secret").Stat()
+	denied(err)
+	_, err = private.Children()
+	denied(err)
+
+	file := home.Join("file")
+	file.MustWriteString("mine")
+	uid, gid, _ := file.Owner()
+	Equals(t, []int{1000, 1000}, []int{uid, gid})
+	file.MustChmod(0444)
+	denied(file.WriteString("changed"))
+	file.MustChmod(0644)
+	file.MustWriteString("changed")
+	denied(file.Chown(0, -1))
+	denied(shared.Chmod(0666))
+	file.MustRename(home.Join("renamed"))
+	denied(home.Join("renamed").Rename(tree.Join("renamed")))
+	denied(home.Join("mine").MustMake().Rename(full))
+	Equals(t, "inner", full.Join("inner").MustReadString())
+
+	sys.EnforcePermissions(false)
+	shared.MustWriteString("changed")
+	output.Join("file").MustWriteString("output")
+}
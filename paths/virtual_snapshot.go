@@ -0,0 +1,169 @@
+package paths
+
+import (
+	"bytes"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+)
+
+// VirtualSnapshot is a deep copy of the contents of a VirtualSystem at a point in time. It is not affected by changes to
+// the system it was taken from, and can be restored any number of times.
+type VirtualSnapshot struct {
+	rootDir  *virtualDir
+	rootPath string
+}
+
+// VirtualDiff describes the differences between two virtual systems or snapshots. Each field is a sorted list of
+// absolute paths.
+type VirtualDiff struct {
+	// Entries that only exist in the newer system, including descendants of added directories.
+	Added []string
+
+	// Entries that only exist in the older system, including descendants of removed directories.
+	Removed []string
+
+	// Files whose contents differ, and entries whose types differ.
+	Changed []string
+
+	// Entries whose permissions differ.
+	ModeChanged []string
+
+	// Symlinks whose targets differ.
+	TargetChanged []string
+}
+
+func (d *VirtualDiff) IsEmpty() bool {
+	return len(d.Added)+len(d.Removed)+len(d.Changed)+len(d.ModeChanged)+len(d.TargetChanged) == 0
+}
+
+func (v *VirtualSystem) Snapshot() *VirtualSnapshot {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	return &VirtualSnapshot{v.rootDir.clone(nil), v.rootPath}
+}
+
+// Restore replaces the contents of the system with the contents of the given snapshot. Files that are open when Restore
+// is called are no longer part of the system.
+func (v *VirtualSystem) Restore(snapshot *VirtualSnapshot) {
+	rootDir := snapshot.rootDir.clone(nil)
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.rootDir = rootDir
+}
+
+// Clone returns a new VirtualSystem with the same contents as v, which can then be changed independently.
+func (v *VirtualSystem) Clone() *VirtualSystem {
+	snapshot := v.Snapshot()
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	return &VirtualSystem{
+		rootDir:  snapshot.rootDir,
+		rootPath: snapshot.rootPath,
+		user:     v.user,
+		uid:      v.uid,
+		gid:      v.gid,
+		enforce:  v.enforce,
+		wd:       v.wd,
+		env:      copyEnv(v.env),
+	}
+}
+
+// Diff returns the changes required to turn v into other.
+func (v *VirtualSystem) Diff(other *VirtualSystem) *VirtualDiff {
+	return v.Snapshot().Diff(other.Snapshot())
+}
+
+// Diff returns the changes required to turn s into other.
+func (s *VirtualSnapshot) Diff(other *VirtualSnapshot) (diff *VirtualDiff) {
+	diff = new(VirtualDiff)
+	before, after := s.entries(), other.entries()
+	for path, old := range before {
+		current, exists := after[path]
+		if !exists {
+			diff.Removed = append(diff.Removed, path)
+			continue
+		}
+		oldMode, currentMode := old.entry().mode, current.entry().mode
+		if oldMode.Type() != currentMode.Type() {
+			diff.Changed = append(diff.Changed, path)
+			continue
+		}
+		if oldMode.Perm() != currentMode.Perm() {
+			diff.ModeChanged = append(diff.ModeChanged, path)
+		}
+		switch old := old.(type) {
+		case *virtualFile:
+			if !bytes.Equal(old.contents, current.(*virtualFile).contents) {
+				diff.Changed = append(diff.Changed, path)
+			}
+		case *virtualSymlink:
+			if old.target != current.(*virtualSymlink).target {
+				diff.TargetChanged = append(diff.TargetChanged, path)
+			}
+		}
+	}
+	for path := range after {
+		if _, exists := before[path]; !exists {
+			diff.Added = append(diff.Added, path)
+		}
+	}
+	for _, paths := range [][]string{diff.Added, diff.Removed, diff.Changed, diff.ModeChanged, diff.TargetChanged} {
+		sort.Strings(paths)
+	}
+	return
+}
+
+// entries maps the absolute paths of all entries in the snapshot, except its root, to those entries.
+func (s *VirtualSnapshot) entries() map[string]virtualEntry {
+	entries := make(map[string]virtualEntry)
+	var add func(dir *virtualDir, path string)
+	add = func(dir *virtualDir, path string) {
+		for _, child := range dir.children {
+			childPath := filepath.Join(path, child.entry().name)
+			entries[childPath] = child
+			if childDir, ok := child.(*virtualDir); ok {
+				add(childDir, childPath)
+			}
+		}
+	}
+	add(s.rootDir, s.rootPath)
+	return entries
+}
+
+// clone returns a deep copy of d, with the given parent.
+func (d *virtualDir) clone(parent *virtualDir) *virtualDir {
+	return d.cloneInodes(parent, make(map[*virtualInode]*virtualInode))
+}
+
+// cloneInodes returns a deep copy of d, with the given parent. Each inode is copied once, and its copy recorded in
+// inodes, so that hard links in d are still hard links in the copy.
+func (d *virtualDir) cloneInodes(parent *virtualDir, inodes map[*virtualInode]*virtualInode) *virtualDir {
+	c := &virtualDir{
+		virtualEntryBase: d.virtualEntryBase.clone(parent, inodes),
+		children:         make([]virtualEntry, len(d.children)),
+	}
+	for i, child := range d.children {
+		switch child := child.(type) {
+		case *virtualDir:
+			c.children[i] = child.cloneInodes(c, inodes)
+		case *virtualFile:
+			c.children[i] = &virtualFile{child.virtualEntryBase.clone(c, inodes)}
+		case *virtualSymlink:
+			c.children[i] = &virtualSymlink{child.virtualEntryBase.clone(c, inodes), child.target}
+		}
+	}
+	return c
+}
+
+func (e *virtualEntryBase) clone(parent *virtualDir, inodes map[*virtualInode]*virtualInode) *virtualEntryBase {
+	inode, cloned := inodes[e.virtualInode]
+	if !cloned {
+		copied := *e.virtualInode
+		copied.ino = atomic.AddUint64(&lastVirtualIno, 1)
+		copied.contents = append([]byte(nil), e.contents...)
+		inode = &copied
+		inodes[e.virtualInode] = inode
+	}
+	return &virtualEntryBase{e.name, parent, inode}
+}
@@ -0,0 +1,75 @@
+package paths_test
+
+import (
+	. "github.com/hx/golib/paths"
+	. "github.com/hx/golib/testing"
+	"testing"
+)
+
+func TestVirtualSystem_Snapshot(t *testing.T) {
+	sys := NewVirtualSystem()
+	tree := NewTreeWithSystem(sys)
+	tree.Join("a", "file").MustWriteString("original")
+	tree.Join("b").MustWriteString("b")
+
+	snapshot := sys.Snapshot()
+	tree.Join("a", "file").MustWriteString("changed")
+	tree.Join("c").MustWriteString("c")
+	tree.Join("b").MustDelete()
+
+	sys.Restore(snapshot)
+	Equals(t, "original", tree.Join("a", "file").MustReadString())
+	Assert(t, tree.Join("b").Exists(), "b should be restored")
+	Assert(t, !tree.Join("c").Exists(), "c should not exist")
+
+	// The snapshot should be reusable after the restored system changes.
+	tree.Join("b").MustDelete()
+	sys.Restore(snapshot)
+	Assert(t, tree.Join("b").Exists(), "b should be restored again")
+}
+
+func TestVirtualSystem_Clone(t *testing.T) {
+	sys := NewVirtualSystem()
+	NewTreeWithSystem(sys).Join("file").MustWriteString("original")
+
+	clone := sys.Clone()
+	NewTreeWithSystem(clone).Join("file").MustWriteString("changed")
+
+	Equals(t, "original", NewTreeWithSystem(sys).Join("file").MustReadString())
+	Equals(t, "changed", NewTreeWithSystem(clone).Join("file").MustReadString())
+}
+
+func TestVirtualSystem_Diff(t *testing.T) {
+	sys := NewVirtualSystem()
+	tree := NewTreeWithSystem(sys)
+	tree.Join("same").MustWriteString("same")
+	tree.Join("changed").MustWriteString("before")
+	tree.Join("removed", "file").MustWriteString("removed")
+	tree.Join("chmod").MustWriteString("chmod")
+	tree.Join("type").MustWriteString("file")
+	if LocalSystem.SupportsSymlinks() {
+		tree.Join("same").MustSymlinkTo(tree.Join("link"))
+	}
+
+	before := sys.Snapshot()
+	Assert(t, before.Diff(sys.Snapshot()).IsEmpty(), "diff should be empty")
+
+	tree.Join("changed").MustWriteString("after")
+	tree.Join("removed").MustDelete()
+	tree.Join("chmod").MustChmod(0600)
+	tree.Join("type").MustDelete()
+	tree.Join("type").MustMake()
+	tree.Join("added", "file").MustWriteString("added")
+	expected := &VirtualDiff{
+		Added:       []string{tree.Join("added").String(), tree.Join("added", "file").String()},
+		Removed:     []string{tree.Join("removed").String(), tree.Join("removed", "file").String()},
+		Changed:     []string{tree.Join("changed").String(), tree.Join("type").String()},
+		ModeChanged: []string{tree.Join("chmod").String()},
+	}
+	if LocalSystem.SupportsSymlinks() {
+		tree.Join("changed").MustSymlinkTo(tree.Join("link"))
+		expected.TargetChanged = []string{tree.Join("link").String()}
+	}
+
+	Equals(t, expected, before.Diff(sys.Snapshot()))
+}
@@ -3,7 +3,6 @@ package paths
 import (
 	"io/fs"
 	"os"
-	"time"
 )
 
 type virtualSymlink struct {
@@ -12,15 +11,12 @@ type virtualSymlink struct {
 }
 
 func newVirtualSymlink(name string, parent *virtualDir, perm os.FileMode, target string) *virtualSymlink {
-	now := time.Now()
 	return &virtualSymlink{
 		target: target,
 		virtualEntryBase: &virtualEntryBase{
-			name:     name,
-			mode:     perm.Perm() | fs.ModeSymlink,
-			accessed: now,
-			modified: now,
-			parent:   parent,
+			name:         name,
+			parent:       parent,
+			virtualInode: newVirtualInode(perm.Perm() | fs.ModeSymlink),
 		},
 	}
 }
@@ -34,7 +30,7 @@ func (s *virtualSymlink) resolve() virtualEntry { return s.parent.resolve(s.targ
 func (s *virtualSymlink) resolveRecursive() virtualEntry {
 	var (
 		entry virtualEntry = s
-		trail              = []*virtualSymlink{s}
+		trail []*virtualSymlink
 	)
 	for {
 		if link, ok := entry.(*virtualSymlink); ok {
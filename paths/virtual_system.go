@@ -1,16 +1,27 @@
 package paths
 
 import (
+	"errors"
 	"os"
 	"os/user"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
+// VirtualSystem is an in-memory System. It is safe for concurrent use by multiple goroutines.
 type VirtualSystem struct {
 	rootDir  *virtualDir
 	rootPath string
+	mutex    sync.RWMutex
+	locks    inProcessLocks
+	user     *user.User
+	uid      int
+	gid      int
+	enforce  bool
+	wd       string
+	env      map[string]string
 }
 
 func NewVirtualSystem() *VirtualSystem {
@@ -22,36 +33,171 @@ func NewVirtualSystem() *VirtualSystem {
 
 func (v *VirtualSystem) Root() string { return v.rootPath }
 
+// Link creates newname as a hard link to oldname. Hard links share their contents, modes, times and owners. As with
+// os.Link, symlinks are not followed, and directories can't be linked.
+func (v *VirtualSystem) Link(oldname, newname string) error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	return linkError("link", oldname, newname, v.link(oldname, newname))
+}
+
+func (v *VirtualSystem) link(oldname, newname string) error {
+	if err := v.traverse(oldname); err != nil {
+		return err
+	}
+	if err := v.traverse(newname); err != nil {
+		return err
+	}
+	entry := v.resolve(oldname)
+	if entry == nil {
+		return ErrPathNotFound
+	}
+	if v.resolve(newname) != nil {
+		return ErrFileExists
+	}
+	dir, name, err := v.dirAndName(newname)
+	if err != nil {
+		return err
+	}
+	if err = v.check(dir, permWrite|permExecute); err != nil {
+		return err
+	}
+	base := &virtualEntryBase{name, dir, entry.entry().virtualInode}
+	base.links++
+	switch entry := entry.(type) {
+	case *virtualFile:
+		dir.children = append(dir.children, &virtualFile{base})
+	case *virtualSymlink:
+		dir.children = append(dir.children, &virtualSymlink{base, entry.target})
+	default:
+		return ErrDirectory
+	}
+	return nil
+}
+
 func (v *VirtualSystem) Lstat(name string) (os.FileInfo, error) {
-	entry := v.rootDir.resolve(name)
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	if err := v.traverse(name); err != nil {
+		return nil, pathError("lstat", name, err)
+	}
+	entry := v.resolve(name)
 	if entry == nil {
-		return nil, ErrPathNotFound
+		return nil, pathError("lstat", name, ErrPathNotFound)
 	}
-	return entry, nil
+	return newVirtualInfo(entry), nil
+}
+
+// Stat returns information about name, following symlinks.
+func (v *VirtualSystem) Stat(name string) (os.FileInfo, error) {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	if err := v.traverse(name); err != nil {
+		return nil, pathError("stat", name, err)
+	}
+	entry := v.resolve(name)
+	if entry == nil {
+		return nil, pathError("stat", name, ErrPathNotFound)
+	}
+	info := newVirtualInfo(entry)
+	if link, ok := entry.(*virtualSymlink); ok {
+		if entry = link.resolveRecursive(); entry == nil {
+			return nil, pathError("stat", name, ErrBrokenLink)
+		}
+		info = newVirtualInfo(entry)
+		info.name = link.name
+	}
+	return info, nil
 }
 
 func (v *VirtualSystem) Chmod(name string, mode os.FileMode) error {
-	entry := v.rootDir.resolve(name)
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if err := v.traverse(name); err != nil {
+		return pathError("chmod", name, err)
+	}
+	entry := v.resolve(name)
 	if entry == nil {
-		return ErrPathNotFound
+		return pathError("chmod", name, ErrPathNotFound)
+	}
+	if err := v.checkOwner(entry); err != nil {
+		return pathError("chmod", name, err)
 	}
 	base := entry.entry()
 	base.mode = mode.Perm() | base.mode.Type()
 	return nil
 }
 
-func (v *VirtualSystem) Chtimes(name string, atime time.Time, mtime time.Time) error {
-	entry := v.rootDir.resolve(name)
+// Chown changes the owner of name, following symlinks. A uid or gid of -1 leaves that value unchanged.
+func (v *VirtualSystem) Chown(name string, uid, gid int) error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if err := v.traverse(name); err != nil {
+		return pathError("chown", name, err)
+	}
+	entry := v.resolve(name)
+	if link, ok := entry.(*virtualSymlink); ok {
+		entry = link.resolveRecursive()
+	}
+	return pathError("chown", name, v.chown(entry, uid, gid))
+}
+
+// Lchown changes the owner of name, without following symlinks. A uid or gid of -1 leaves that value unchanged.
+func (v *VirtualSystem) Lchown(name string, uid, gid int) error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if err := v.traverse(name); err != nil {
+		return pathError("lchown", name, err)
+	}
+	return pathError("lchown", name, v.chown(v.resolve(name), uid, gid))
+}
+
+// chown changes the owner of entry. Unless the current user is root, they can only change the group of entries they
+// own, and only to their own group.
+func (v *VirtualSystem) chown(entry virtualEntry, uid, gid int) error {
 	if entry == nil {
 		return ErrPathNotFound
 	}
 	base := entry.entry()
+	if v.enforcing() && (uid != -1 && uid != base.uid || gid != -1 && gid != v.gid || base.uid != v.uid) {
+		return ErrPermission
+	}
+	if uid != -1 {
+		base.uid = uid
+	}
+	if gid != -1 {
+		base.gid = gid
+	}
+	return nil
+}
+
+func (v *VirtualSystem) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if err := v.traverse(name); err != nil {
+		return pathError("chtimes", name, err)
+	}
+	entry := v.resolve(name)
+	if entry == nil {
+		return pathError("chtimes", name, ErrPathNotFound)
+	}
+	if err := v.checkOwner(entry); err != nil {
+		return pathError("chtimes", name, err)
+	}
+	base := entry.entry()
 	base.accessed = atime
 	base.modified = mtime
 	return nil
 }
 
+// CurrentUser returns the user set by SetUser, or root if none has been set.
 func (v *VirtualSystem) CurrentUser() (*user.User, error) {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	if v.user != nil {
+		u := *v.user
+		return &u, nil
+	}
 	return &user.User{
 		Uid:      "0",
 		Gid:      "0",
@@ -61,29 +207,73 @@ func (v *VirtualSystem) CurrentUser() (*user.User, error) {
 	}, nil
 }
 
-func (v *VirtualSystem) Getwd() (dir string, err error) { return v.rootPath, nil }
-
 func (v *VirtualSystem) Glob(pattern string) (matches []string, err error) {
-	panic("not implemented")
+	if _, err = filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	return v.globPattern(pattern)
+}
+
+func (v *VirtualSystem) globPattern(pattern string) (matches []string, err error) {
+	if !globHasMeta(pattern) {
+		if v.resolve(pattern) == nil {
+			return nil, nil
+		}
+		return []string{pattern}, nil
+	}
+	dir, file := filepath.Split(pattern)
+	dir = globCleanPath(dir)
+	if !globHasMeta(dir[len(filepath.VolumeName(dir)):]) {
+		return v.glob(dir, file, nil)
+	}
+	if dir == pattern {
+		return nil, filepath.ErrBadPattern
+	}
+	dirMatches, err := v.globPattern(dir)
+	if err != nil {
+		return
+	}
+	for _, d := range dirMatches {
+		if matches, err = v.glob(d, file, matches); err != nil {
+			return
+		}
+	}
+	return
 }
 
 func (v *VirtualSystem) Join(elem ...string) string { return filepath.Join(elem...) }
 
 func (v *VirtualSystem) MkdirAll(path string, perm os.FileMode) error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	return pathError("mkdir", path, v.mkdirAll(path, perm))
+}
+
+func (v *VirtualSystem) mkdirAll(path string, perm os.FileMode) error {
+	path = v.abs(path)
 	dir := v.rootDir
 	parts := strings.Split(v.chompSeparator(path), string(os.PathSeparator))
 	for i, part := range parts {
 		if i == 0 && part == "" {
 			continue
 		}
+		if err := v.check(dir, permExecute); err != nil {
+			return err
+		}
 		entry := dir.resolve(part)
 		switch entry := entry.(type) {
 		case nil:
+			if err := v.check(dir, permWrite); err != nil {
+				return err
+			}
 			for _, part := range parts[i:] {
 				child := newVirtualDir()
 				child.name = part
 				child.mode = child.mode.Type() | perm.Perm()
 				child.parent = dir
+				v.own(child)
 				dir.children = append(dir.children, child)
 				dir = child
 			}
@@ -108,7 +298,24 @@ func (v *VirtualSystem) MkdirAll(path string, perm os.FileMode) error {
 }
 
 func (v *VirtualSystem) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
-	entry := v.rootDir.resolve(name)
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	file, err := v.openFile(name, flag, perm)
+	if err != nil {
+		return nil, pathError("open", name, err)
+	}
+	f := &virtualOpenFile{sys: v, file: file}
+	if flag&os.O_APPEND != 0 {
+		f.offset = len(file.contents)
+	}
+	return f, nil
+}
+
+func (v *VirtualSystem) openFile(name string, flag int, perm os.FileMode) (*virtualFile, error) {
+	if err := v.traverse(name); err != nil {
+		return nil, err
+	}
+	entry := v.resolve(name)
 	if link, ok := entry.(*virtualSymlink); ok {
 		entry = link.resolveRecursive()
 	}
@@ -122,6 +329,18 @@ func (v *VirtualSystem) OpenFile(name string, flag int, perm os.FileMode) (File,
 	if isFile && flag&os.O_EXCL != 0 {
 		return nil, ErrFileExists
 	}
+	if isFile {
+		var want os.FileMode
+		if flag&os.O_WRONLY == 0 {
+			want |= permRead
+		}
+		if isWriteFlag(flag) {
+			want |= permWrite
+		}
+		if err := v.check(file, want); err != nil {
+			return nil, err
+		}
+	}
 	if isFile && flag&os.O_TRUNC != 0 {
 		file.contents = []byte{}
 	}
@@ -130,50 +349,81 @@ func (v *VirtualSystem) OpenFile(name string, flag int, perm os.FileMode) (File,
 		if err != nil {
 			return nil, err
 		}
+		if err = v.check(dir, permWrite|permExecute); err != nil {
+			return nil, err
+		}
 		file = newVirtualFile(name, dir, perm)
+		v.own(file)
 		dir.children = append(dir.children, file)
 	}
-	f := &virtualOpenFile{file: file}
-	if flag&os.O_APPEND != 0 {
-		must1(f.Seek(0, 2))
-	}
-	return f, nil
+	return file, nil
 }
 
 func (v *VirtualSystem) ReadDir(name string) (entries []os.DirEntry, err error) {
-	entry := v.rootDir.resolve(name)
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	if err = v.traverse(name); err != nil {
+		return nil, pathError("open", name, err)
+	}
+	entry := v.resolve(name)
 	if link, ok := entry.(*virtualSymlink); ok {
 		entry = link.resolveRecursive()
 	}
+	if err = v.check(entry, permRead); err != nil {
+		return nil, pathError("open", name, err)
+	}
 	dir, isDir := entry.(*virtualDir)
+	if entry == nil {
+		return nil, pathError("open", name, ErrPathNotFound)
+	}
 	if !isDir {
-		return nil, ErrNonDirectory
+		return nil, pathError("readdir", name, ErrNonDirectory)
 	}
 	entries = make([]os.DirEntry, len(dir.children))
 	for i, e := range dir.children {
-		entries[i] = e
+		entries[i] = newVirtualInfo(e)
 	}
 	return
 }
 
 func (v *VirtualSystem) ReadFile(name string) (b []byte, err error) {
-	file, err := v.OpenFile(name, 0, 0)
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	file, err := v.openFile(name, 0, 0)
 	if err != nil {
-		return
+		return nil, pathError("open", name, err)
 	}
-	return file.(*virtualOpenFile).file.contents, nil
+	file.accessed = time.Now()
+	return append([]byte{}, file.contents...), nil
 }
 
 func (v *VirtualSystem) Readlink(name string) (string, error) {
-	entry := v.rootDir.resolve(name)
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	if err := v.traverse(name); err != nil {
+		return "", pathError("readlink", name, err)
+	}
+	entry := v.resolve(name)
 	if link, ok := entry.(*virtualSymlink); ok {
 		return link.target, nil
 	}
-	return "", ErrNonLink
+	if entry == nil {
+		return "", pathError("readlink", name, ErrPathNotFound)
+	}
+	return "", pathError("readlink", name, ErrNonLink)
 }
 
 func (v *VirtualSystem) Remove(name string) error {
-	entry := v.rootDir.resolve(name)
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	return pathError("remove", name, v.remove(name))
+}
+
+func (v *VirtualSystem) remove(name string) error {
+	if err := v.traverse(name); err != nil {
+		return err
+	}
+	entry := v.resolve(name)
 	if entry == nil {
 		return ErrPathNotFound
 	}
@@ -182,20 +432,60 @@ func (v *VirtualSystem) Remove(name string) error {
 	if parent == nil {
 		return ErrNotWritable
 	}
+	if err := v.check(parent, permWrite|permExecute); err != nil {
+		return err
+	}
+	if err := v.checkRemove(entry); err != nil {
+		return err
+	}
+	detach(entry)
+	unlink(entry)
+	return nil
+}
+
+// discard removes whatever is at name, without checking permissions, so that something else can take its place.
+func (v *VirtualSystem) discard(name string) {
+	if entry := v.resolve(name); entry != nil && entry.entry().parent != nil {
+		detach(entry)
+		unlink(entry)
+	}
+}
+
+// detach removes entry from its parent directory.
+func detach(entry virtualEntry) {
+	base := entry.entry()
+	parent := base.parent
 	for i, child := range parent.children {
 		if child == entry {
 			parent.children = append(parent.children[:i], parent.children[i+1:]...)
 			break
 		}
 	}
-	entryBase.parent = nil
-	return nil
+	base.parent = nil
 }
 
-func (v *VirtualSystem) RemoveAll(path string) error { return v.Remove(path) }
+// RemoveAll is the same as Remove, except that, like os.RemoveAll, it succeeds if path doesn't exist.
+func (v *VirtualSystem) RemoveAll(path string) error {
+	if err := v.Remove(path); !errors.Is(err, ErrPathNotFound) {
+		return err
+	}
+	return nil
+}
 
 func (v *VirtualSystem) Rename(oldpath, newpath string) error {
-	entry := v.rootDir.resolve(oldpath)
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	return linkError("rename", oldpath, newpath, v.rename(oldpath, newpath))
+}
+
+func (v *VirtualSystem) rename(oldpath, newpath string) error {
+	if err := v.traverse(oldpath); err != nil {
+		return err
+	}
+	if err := v.traverse(newpath); err != nil {
+		return err
+	}
+	entry := v.resolve(oldpath)
 	if entry == nil {
 		return ErrPathNotFound
 	}
@@ -208,8 +498,19 @@ func (v *VirtualSystem) Rename(oldpath, newpath string) error {
 	if err != nil {
 		return err
 	}
-	_ = v.Remove(oldpath)
-	_ = v.Remove(newpath)
+	if err = v.check(oldParent, permWrite|permExecute); err != nil {
+		return err
+	}
+	if err = v.check(newParent, permWrite|permExecute); err != nil {
+		return err
+	}
+	if existing := v.resolve(newpath); existing != nil && existing != entry {
+		if err = v.checkRemove(existing); err != nil {
+			return err
+		}
+	}
+	detach(entry)
+	v.discard(newpath)
 	entryBase.name = newName
 	entryBase.parent = newParent
 	newParent.children = append(newParent.children, entry)
@@ -219,22 +520,47 @@ func (v *VirtualSystem) Rename(oldpath, newpath string) error {
 func (v *VirtualSystem) SupportsSymlinks() bool { return LocalSystem.SupportsSymlinks() }
 
 func (v *VirtualSystem) Symlink(oldname, newname string) error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if err := v.traverse(newname); err != nil {
+		return linkError("symlink", oldname, newname, err)
+	}
 	dir, name, err := v.dirAndName(newname)
+	if err == nil {
+		err = v.check(dir, permWrite|permExecute)
+	}
 	if err != nil {
-		return err
+		return linkError("symlink", oldname, newname, err)
 	}
-	_ = v.Remove(newname)
+	v.discard(newname)
 	link := newVirtualSymlink(name, dir, 0644, oldname)
+	v.own(link)
 	dir.children = append(dir.children, link)
 	return nil
 }
 
+// abs returns name if it's absolute, or name joined to the working directory otherwise.
+func (v *VirtualSystem) abs(name string) string {
+	if isAbs(name) {
+		return name
+	}
+	if v.wd == "" {
+		return filepath.Join(v.rootPath, name)
+	}
+	return filepath.Join(v.wd, name)
+}
+
+// resolve returns the entry at name, which is resolved against the working directory if it's relative, or nil if
+// there's no such entry.
+func (v *VirtualSystem) resolve(name string) virtualEntry { return v.rootDir.resolve(v.abs(name)) }
+
 func (v *VirtualSystem) dirAndName(path string) (dir *virtualDir, name string, err error) {
+	path = v.abs(path)
 	splitAt := strings.LastIndexByte(path, os.PathSeparator)
 	if splitAt == -1 {
 		return nil, "", ErrInvalid
 	}
-	newParentEntry := v.rootDir.resolve(path[:splitAt])
+	newParentEntry := v.resolve(path[:splitAt])
 	if link, ok := newParentEntry.(*virtualSymlink); ok {
 		newParentEntry = link.resolveRecursive()
 	}
@@ -1,12 +1,16 @@
 package paths
 
 import (
+	"errors"
+	"fmt"
 	. "github.com/hx/golib/testing"
 	"io"
 	"io/fs"
 	"os"
+	"path/filepath"
 	"regexp"
 	"runtime"
+	"sync"
 	"testing"
 	"time"
 )
@@ -125,11 +129,11 @@ func TestVirtualSystem_ReadDir(t *testing.T) {
 	Ok(t, err)
 	Equals(t, 2, len(entries))
 
-	foo := entries[0].(*virtualFile)
-	Equals(t, "foo", foo.name)
+	Equals(t, "foo", entries[0].Name())
+	Equals(t, false, entries[0].IsDir())
 
-	bar := entries[1].(*virtualDir)
-	Equals(t, "bar", bar.name)
+	Equals(t, "bar", entries[1].Name())
+	Equals(t, true, entries[1].IsDir())
 }
 
 func TestVirtualSystem_ReadFile(t *testing.T) {
@@ -149,6 +153,9 @@ func TestVirtualSystem_Remove(t *testing.T) {
 	Equals(t, 1, len(sys.rootDir.children))
 	Ok(t, sys.Remove("bar"))
 	Equals(t, 0, len(sys.rootDir.children))
+	err := sys.Remove("missing")
+	Assert(t, errors.Is(err, ErrPathNotFound), "expected ErrPathNotFound, got %v", err)
+	Ok(t, sys.RemoveAll("missing"))
 }
 
 func TestVirtualSystem_Rename(t *testing.T) {
@@ -174,3 +181,80 @@ func TestVirtualSystem_Symlink(t *testing.T) {
 	Ok(t, sys.Symlink(reslash("/foo"), reslash("/foo/back2foo")))
 	Equals(t, reslash("/foo"), sys.rootDir.children[0].(*virtualDir).children[0].(*virtualSymlink).target)
 }
+
+func TestVirtualSystem_Glob(t *testing.T) {
+	sys, tree := testSys()
+	tree.Join("src", "b.go").MustTouch()
+	tree.Join("src", "a.go").MustTouch()
+	tree.Join("src", "a_test.go").MustTouch()
+	tree.Join("src", "sub", "c.go").MustTouch()
+	tree.Join("lib", "d.go").MustTouch()
+	tree.Join("lib", "readme.md").MustTouch()
+	Ok(t, sys.Symlink(reslash("/src/sub"), reslash("/lib/linked")))
+
+	e := func(expected []string, pattern string) {
+		t.Helper()
+		for i, v := range expected {
+			expected[i] = tree.Join(reslash(v)).String()
+		}
+		matches, err := sys.Glob(tree.Join(reslash(pattern)).String())
+		Ok(t, err)
+		if len(expected) == 0 {
+			expected = nil
+		}
+		Equals(t, expected, matches)
+	}
+
+	e([]string{"/src/a.go", "/src/a_test.go", "/src/b.go"}, "/src/*.go")
+	e([]string{"/src/a.go", "/src/b.go"}, "/src/[ab].go")
+	e([]string{"/lib/d.go", "/src/a.go", "/src/a_test.go", "/src/b.go"}, "/*/*.go")
+	e([]string{"/lib/linked/c.go", "/src/sub/c.go"}, "/*/*/c.go")
+	e([]string{"/src/a.go"}, "/src/a.go")
+	e(nil, "/src/nope.go")
+	e(nil, "/nope/*.go")
+
+	_, err := sys.Glob("[")
+	Equals(t, filepath.ErrBadPattern, err)
+}
+
+func TestVirtualSystem_Concurrent(t *testing.T) {
+	_, tree := testSys()
+	// Goroutines other than the test's own mustn't fail it, so they report errors here instead.
+	errs := make(chan error, 20)
+	work := func(i int) error {
+		dir := tree.Join("out", fmt.Sprint(i%4))
+		file := dir.Join(fmt.Sprint(i))
+		if err := file.WriteString("hello"); err != nil {
+			return err
+		}
+		f, err := file.Append()
+		if err != nil {
+			return err
+		}
+		if _, err = f.Write([]byte(" world")); err != nil {
+			return err
+		}
+		if err = f.Close(); err != nil {
+			return err
+		}
+		if contents, err := file.ReadString(); err != nil || contents != "hello world" {
+			return fmt.Errorf("read %q from %s: %v", contents, file, err)
+		}
+		_, _ = tree.Join("out").Glob("*/*")
+		return dir.Walk(func(*Path, os.FileInfo, error) error { return nil })
+	}
+	var wait sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			errs <- work(i)
+		}(i)
+	}
+	wait.Wait()
+	close(errs)
+	for err := range errs {
+		Ok(t, err)
+	}
+	Equals(t, 20, len(tree.MustGlob(reslash("out/*/*"))))
+}
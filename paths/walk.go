@@ -0,0 +1,123 @@
+package paths
+
+import (
+	"os"
+	"sort"
+)
+
+// WalkFunc is called by Walk for each path it visits. If info could not be obtained, it is nil, and err describes the
+// problem. If a directory could not be read, the function is called a second time for that directory with err set.
+//
+// Returning SkipDir for a directory prevents its contents from being visited. Returning SkipDir for any other path
+// skips its remaining siblings. Returning SkipAll stops the walk. Any other error stops the walk and is returned from
+// Walk.
+type WalkFunc func(path *Path, info os.FileInfo, err error) error
+
+const (
+	SkipDir Error = "skip this directory"
+	SkipAll Error = "skip everything"
+)
+
+type walker struct {
+	fn          WalkFunc
+	followLinks bool
+	ancestors   []string
+}
+
+// Walk visits p and all its descendants depth-first, in lexical order, calling fn for each of them. Symlinks are
+// visited, but not followed.
+func (p *Path) Walk(fn WalkFunc) error { return p.walk(fn, false) }
+
+// WalkFollowingLinks is like Walk, but descends into symlinks that resolve to directories. A link that would lead back
+// to one of its own ancestors is visited, but not descended into.
+func (p *Path) WalkFollowingLinks(fn WalkFunc) error { return p.walk(fn, true) }
+
+func (p *Path) MustWalk(fn WalkFunc)               { must(p.Walk(fn)) }
+func (p *Path) MustWalkFollowingLinks(fn WalkFunc) { must(p.WalkFollowingLinks(fn)) }
+
+func (p *Path) walk(fn WalkFunc, followLinks bool) (err error) {
+	info, err := p.Stat()
+	if err != nil {
+		err = fn(p, nil, err)
+	} else {
+		err = (&walker{fn: fn, followLinks: followLinks}).walk(p, info)
+	}
+	if err == SkipDir || err == SkipAll {
+		err = nil
+	}
+	return
+}
+
+func (w *walker) walk(path *Path, info os.FileInfo) error {
+	isDir, id := w.inspect(path, info)
+	err := w.fn(path, info, nil)
+	if err == SkipDir && isDir {
+		return nil
+	}
+	if err != nil || !isDir || w.isAncestor(id) {
+		return err
+	}
+
+	entries, err := path.tree.sys.ReadDir(path.path)
+	if err != nil {
+		if err = w.fn(path, info, err); err == SkipDir {
+			err = nil
+		}
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	if id != "" {
+		w.ancestors = append(w.ancestors, id)
+		defer func() { w.ancestors = w.ancestors[:len(w.ancestors)-1] }()
+	}
+
+	for _, entry := range entries {
+		child := path.Join(entry.Name())
+		childInfo, err := child.Stat()
+		if err != nil {
+			err = w.fn(child, nil, err)
+		} else {
+			err = w.walk(child, childInfo)
+		}
+		if err == SkipDir {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inspect determines whether the given path should be treated as a directory. When following links, it also returns
+// an identifier that will be the same for any paths that lead to the same directory.
+func (w *walker) inspect(path *Path, info os.FileInfo) (isDir bool, id string) {
+	isLink := info.Mode()&os.ModeSymlink != 0
+	if !w.followLinks {
+		return info.IsDir(), ""
+	}
+	if !isLink && !info.IsDir() {
+		return false, ""
+	}
+	resolved, err := path.EvalSymlinks()
+	if err != nil {
+		return !isLink, path.path
+	}
+	if isLink {
+		stat, err := resolved.Stat()
+		if err != nil || !stat.IsDir() {
+			return false, ""
+		}
+	}
+	return true, resolved.path
+}
+
+func (w *walker) isAncestor(id string) bool {
+	for _, ancestor := range w.ancestors {
+		if ancestor == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package paths_test
+
+import (
+	. "github.com/hx/golib/paths"
+	. "github.com/hx/golib/testing"
+	"os"
+	"strings"
+	"testing"
+)
+
+// eachSystem runs fn against an empty directory on the local file system, and against the root of a virtual system.
+func eachSystem(t *testing.T, fn func(t *testing.T, tree *Tree, dir *Path)) {
+	t.Run("local", func(t *testing.T) {
+		tree := NewTree()
+		fn(t, tree, tree.Join(t.TempDir()))
+	})
+	t.Run("virtual", func(t *testing.T) {
+		tree := NewTreeWithSystem(NewVirtualSystem())
+		fn(t, tree, tree.Path)
+	})
+}
+
+// relName returns the slash-separated name of path relative to dir.
+func relName(dir, path *Path) string {
+	rel := strings.TrimPrefix(strings.TrimPrefix(path.String(), dir.String()), string(os.PathSeparator))
+	return strings.ReplaceAll(rel, string(os.PathSeparator), "/")
+}
+
+func walkFixture(dir *Path) {
+	dir.Join("b", "two").MustWriteString("2")
+	dir.Join("b", "one").MustWriteString("1")
+	dir.Join("a", "deep", "three").MustWriteString("3")
+	dir.Join("c").MustWriteString("c")
+	if LocalSystem.SupportsSymlinks() {
+		dir.Join("b").MustSymlinkTo(dir.Join("a", "deep", "link"))
+		dir.Join("a").MustSymlinkTo(dir.Join("b", "loop"))
+		dir.Join("nowhere").MustSymlinkTo(dir.Join("broken"))
+	}
+}
+
+func walkNames(t *testing.T, dir *Path, follow bool, fn func(rel string) error) (names []string) {
+	t.Helper()
+	walk := dir.Walk
+	if follow {
+		walk = dir.WalkFollowingLinks
+	}
+	Ok(t, walk(func(path *Path, info os.FileInfo, err error) error {
+		Ok(t, err)
+		rel := relName(dir, path)
+		names = append(names, rel)
+		if fn != nil {
+			return fn(rel)
+		}
+		return nil
+	}))
+	return
+}
+
+func TestPath_Walk(t *testing.T) {
+	if !LocalSystem.SupportsSymlinks() {
+		t.Skip("symlinks not supported")
+	}
+	eachSystem(t, func(t *testing.T, _ *Tree, dir *Path) {
+		walkFixture(dir)
+		Equals(t, []string{
+			"", "a", "a/deep", "a/deep/link", "a/deep/three", "b", "b/loop", "b/one", "b/two", "broken", "c",
+		}, walkNames(t, dir, false, nil))
+	})
+}
+
+func TestPath_WalkFollowingLinks(t *testing.T) {
+	if !LocalSystem.SupportsSymlinks() {
+		t.Skip("symlinks not supported")
+	}
+	eachSystem(t, func(t *testing.T, _ *Tree, dir *Path) {
+		walkFixture(dir)
+		Equals(t, []string{
+			"",
+			"a", "a/deep", "a/deep/link", "a/deep/link/loop", "a/deep/link/one", "a/deep/link/two", "a/deep/three",
+			"b", "b/loop", "b/loop/deep", "b/loop/deep/link", "b/loop/deep/three", "b/one", "b/two",
+			"broken", "c",
+		}, walkNames(t, dir, true, nil))
+	})
+}
+
+func TestPath_Walk_Skip(t *testing.T) {
+	if !LocalSystem.SupportsSymlinks() {
+		t.Skip("symlinks not supported")
+	}
+	eachSystem(t, func(t *testing.T, _ *Tree, dir *Path) {
+		walkFixture(dir)
+		Equals(t, []string{"", "a", "b", "b/loop", "b/one", "broken", "c"}, walkNames(t, dir, false, func(rel string) error {
+			switch rel {
+			case "a", "b/one":
+				return SkipDir
+			case "c":
+				return SkipAll
+			}
+			return nil
+		}))
+	})
+}
@@ -0,0 +1,202 @@
+package paths
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Watcher observes a path and its descendants for changes made outside the paths API, and dispatches events describing
+// them to its tree's listeners. Watchers satisfy bg.Service, so they can be run as part of a bg.Group.
+type Watcher interface {
+	// Run watches for changes until Stop is called, or an error occurs.
+	Run() error
+
+	// Stop signals Run to return.
+	Stop()
+}
+
+const DefaultPollInterval = time.Second
+
+// ErrWatchOverflow is returned by a Watcher's Run method if changes occur faster than they can be observed.
+const ErrWatchOverflow Error = "too many changes to watch"
+
+type pollingWatcher struct {
+	path     *Path
+	interval time.Duration
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// PollingWatcher returns a Watcher that periodically walks p and compares what it finds with what it found last time.
+// It works with any System. Files that are removed and added with the same size, mode and modification time in the same
+// interval are reported as renamed, as are directories whose contents are the same.
+func (p *Path) PollingWatcher(interval time.Duration) Watcher {
+	return &pollingWatcher{
+		path:     p,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+func (w *pollingWatcher) Stop() { w.stopOnce.Do(func() { close(w.stop) }) }
+
+func (w *pollingWatcher) Run() error {
+	prev := w.scan()
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return nil
+		case <-ticker.C:
+		}
+		next := w.scan()
+		w.compare(prev, next)
+		prev = next
+	}
+}
+
+type pollState struct {
+	mode     os.FileMode
+	size     int64
+	modified time.Time
+}
+
+type pollSnapshot map[string]pollState
+
+func (w *pollingWatcher) scan() pollSnapshot {
+	snapshot := make(pollSnapshot)
+	_ = w.path.Walk(func(path *Path, info os.FileInfo, err error) error {
+		if err == nil {
+			snapshot[w.path.nameOf(path)] = pollState{info.Mode(), info.Size(), info.ModTime()}
+		}
+		return nil
+	})
+	return snapshot
+}
+
+func (w *pollingWatcher) compare(prev, next pollSnapshot) {
+	var removed, added, rewritten []string
+	for name, old := range prev {
+		if current, ok := next[name]; !ok || current.mode.Type() != old.mode.Type() {
+			removed = append(removed, name)
+		}
+	}
+	for name, current := range next {
+		old, ok := prev[name]
+		switch {
+		case !ok || current.mode.Type() != old.mode.Type():
+			added = append(added, name)
+		case !current.mode.IsDir() && current != old:
+			rewritten = append(rewritten, name)
+		}
+	}
+	sort.Strings(removed)
+	sort.Strings(added)
+	sort.Strings(rewritten)
+
+	removed = topLevelNames(removed)
+	removed, added = w.renames(removed, added, prev, next)
+
+	for _, name := range removed {
+		path := w.path.Join(name)
+		if prev[name].mode.IsDir() {
+			w.path.tree.dispatch(DeleteDirEvent{newEvent(path)})
+		} else {
+			w.path.tree.dispatch(DeleteFileEvent{newFileEvent(path, prev[name].size)})
+		}
+	}
+	for _, name := range added {
+		if state := next[name]; !state.mode.IsDir() {
+			w.path.tree.dispatch(CreateFileEvent{newFileEvent(w.path.Join(name), state.size)})
+		}
+	}
+	for _, name := range rewritten {
+		w.path.tree.dispatch(RewriteFileEvent{newFileEvent(w.path.Join(name), next[name].size)})
+	}
+}
+
+// renames dispatches RenameEvent for each removed name that can be paired with exactly one added name, and returns the
+// names that could not be paired. Added names inside renamed directories are also excluded from the result.
+func (w *pollingWatcher) renames(removed, added []string, prev, next pollSnapshot) (remainingRemoved, remainingAdded []string) {
+	addedBySignature := make(map[string][]string)
+	for _, name := range topLevelNames(added) {
+		sig := next.signature(name)
+		addedBySignature[sig] = append(addedBySignature[sig], name)
+	}
+	renamed := make(map[string]bool)
+	for _, name := range removed {
+		candidates := addedBySignature[prev.signature(name)]
+		if len(candidates) != 1 || renamed[candidates[0]] {
+			remainingRemoved = append(remainingRemoved, name)
+			continue
+		}
+		renamed[candidates[0]] = true
+		w.path.tree.dispatch(RenameEvent{newTargetEvent(w.path.Join(name), w.path.Join(candidates[0]))})
+	}
+	for _, name := range added {
+		if !renamed[name] && !hasAncestorIn(name, renamed) {
+			remainingAdded = append(remainingAdded, name)
+		}
+	}
+	return
+}
+
+// signature returns a string that will be equal for two entries if they are likely to be the same entry under a
+// different name.
+func (s pollSnapshot) signature(name string) string {
+	state := s[name]
+	if !state.mode.IsDir() {
+		return state.mode.String() + " " + strconv.FormatInt(state.size, 10) + " " + state.modified.String()
+	}
+	prefix := dirPrefix(name)
+	var lines []string
+	for other := range s {
+		if other != name && strings.HasPrefix(other, prefix) {
+			lines = append(lines, other[len(prefix):]+"\x00"+s.signature(other))
+		}
+	}
+	sort.Strings(lines)
+	return state.mode.String() + "\x00" + strings.Join(lines, "\x00")
+}
+
+// topLevelNames removes from names any name that is inside a directory that is also in names.
+func topLevelNames(names []string) (topLevel []string) {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	for _, name := range names {
+		if !hasAncestorIn(name, set) {
+			topLevel = append(topLevel, name)
+		}
+	}
+	return
+}
+
+func hasAncestorIn(name string, names map[string]bool) bool {
+	for name != "" {
+		if i := strings.LastIndexByte(name, os.PathSeparator); i == -1 {
+			name = ""
+		} else {
+			name = name[:i]
+		}
+		if names[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// dirPrefix returns the prefix shared by the names of all descendants of the given name. Names are relative to the
+// watched path, so the watched path's own name is empty.
+func dirPrefix(name string) string {
+	if name == "" {
+		return ""
+	}
+	return name + string(os.PathSeparator)
+}
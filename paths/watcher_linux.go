@@ -0,0 +1,286 @@
+//go:build linux
+// +build linux
+
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Watcher returns a Watcher for p. If p's tree uses LocalSystem, it uses inotify. Otherwise, it is a PollingWatcher
+// using DefaultPollInterval.
+func (p *Path) Watcher() Watcher {
+	if _, isLocal := p.tree.sys.(local); isLocal {
+		return &inotifyWatcher{
+			path: p,
+			stop: make(chan struct{}),
+		}
+	}
+	return p.PollingWatcher(DefaultPollInterval)
+}
+
+// inotifyMoveTimeout is how long an IN_MOVED_FROM waits for a matching IN_MOVED_TO, which the kernel may deliver in a
+// later read, before it is treated as a deletion.
+const inotifyMoveTimeout = 10 * time.Millisecond
+
+const inotifyMask = syscall.IN_CREATE |
+	syscall.IN_CLOSE_WRITE |
+	syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM |
+	syscall.IN_MOVED_TO |
+	syscall.IN_ONLYDIR |
+	syscall.IN_DONT_FOLLOW
+
+type inotifyWatcher struct {
+	path     *Path
+	stop     chan struct{}
+	stopOnce sync.Once
+	fd       int
+	watches  map[int32]string // Watch descriptors to the directories they watch
+	sizes    map[string]int64 // Last known sizes of files, for DeleteFileEvent
+	created  map[string]bool  // Files that have been created, but not yet closed after writing
+	moved    *inotifyMove     // An IN_MOVED_FROM that may be followed by a matching IN_MOVED_TO
+}
+
+type inotifyMove struct {
+	cookie uint32
+	path   string
+	isDir  bool
+}
+
+func (w *inotifyWatcher) Stop() { w.stopOnce.Do(func() { close(w.stop) }) }
+
+func (w *inotifyWatcher) Run() (err error) {
+	w.fd, err = syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return os.NewSyscallError("inotify_init1", err)
+	}
+	// A non-blocking file uses the runtime's poller, so closing it will interrupt a pending Read.
+	file := os.NewFile(uintptr(w.fd), "inotify")
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-w.stop:
+		case <-done:
+		}
+		_ = file.Close()
+	}()
+
+	w.watches = make(map[int32]string)
+	w.sizes = make(map[string]int64)
+	w.created = make(map[string]bool)
+	if err = w.add(w.path.path, false); err != nil {
+		return
+	}
+
+	buf := make([]byte, 64*1024)
+	for {
+		if w.moved != nil {
+			_ = file.SetReadDeadline(time.Now().Add(inotifyMoveTimeout))
+		} else {
+			_ = file.SetReadDeadline(time.Time{})
+		}
+		n, err := file.Read(buf)
+		if err != nil {
+			select {
+			case <-w.stop:
+				return nil
+			default:
+			}
+			if os.IsTimeout(err) {
+				w.flushMove()
+				continue
+			}
+			return err
+		}
+		if err = w.process(buf[:n]); err != nil {
+			return err
+		}
+	}
+}
+
+// add watches dir and all directories within it. If dispatch is true, it dispatches CreateFileEvent for all the files
+// it finds.
+func (w *inotifyWatcher) add(dir string, dispatch bool) error {
+	return w.path.Join(dir).Walk(func(path *Path, info os.FileInfo, err error) error {
+		if err != nil {
+			// The entry may have gone before we got to it.
+			return nil
+		}
+		if info.IsDir() {
+			wd, err := syscall.InotifyAddWatch(w.fd, path.path, inotifyMask)
+			if err != nil {
+				if err == syscall.ENOENT || err == syscall.ENOTDIR {
+					return SkipDir
+				}
+				return os.NewSyscallError("inotify_add_watch", err)
+			}
+			w.watches[int32(wd)] = path.path
+			return nil
+		}
+		w.sizes[path.path] = info.Size()
+		if dispatch {
+			w.path.tree.dispatch(CreateFileEvent{newFileEvent(path, info.Size())})
+		}
+		return nil
+	})
+}
+
+func (w *inotifyWatcher) process(buf []byte) error {
+	for offset := 0; offset+syscall.SizeofInotifyEvent <= len(buf); {
+		raw := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+		nameBytes := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(raw.Len)]
+		offset += syscall.SizeofInotifyEvent + int(raw.Len)
+
+		if raw.Mask&syscall.IN_Q_OVERFLOW != 0 {
+			return ErrWatchOverflow
+		}
+		if raw.Mask&syscall.IN_IGNORED != 0 {
+			delete(w.watches, raw.Wd)
+			continue
+		}
+		dir, ok := w.watches[raw.Wd]
+		if !ok {
+			continue
+		}
+		var (
+			name  = filepath.Join(dir, strings.TrimRight(string(nameBytes), "\x00"))
+			isDir = raw.Mask&syscall.IN_ISDIR != 0
+			err   error
+		)
+		if raw.Mask&syscall.IN_MOVED_TO != 0 && w.moved != nil && w.moved.cookie == raw.Cookie {
+			w.rename(w.moved.path, name)
+			w.moved = nil
+			continue
+		}
+		w.flushMove()
+		switch {
+		case raw.Mask&syscall.IN_MOVED_FROM != 0:
+			w.moved = &inotifyMove{raw.Cookie, name, isDir}
+		case raw.Mask&syscall.IN_MOVED_TO != 0:
+			err = w.appear(name, isDir, true)
+		case raw.Mask&syscall.IN_CREATE != 0:
+			err = w.appear(name, isDir, false)
+		case raw.Mask&syscall.IN_CLOSE_WRITE != 0:
+			w.written(name)
+		case raw.Mask&syscall.IN_DELETE != 0:
+			w.remove(name, isDir)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *inotifyWatcher) appear(name string, isDir, moved bool) error {
+	if isDir {
+		return w.add(name, true)
+	}
+	path := w.path.Join(name)
+	stat, err := path.Stat()
+	if err != nil {
+		return nil
+	}
+	w.sizes[name] = stat.Size()
+	if moved || !stat.Mode().IsRegular() {
+		w.path.tree.dispatch(CreateFileEvent{newFileEvent(path, stat.Size())})
+	} else {
+		w.created[name] = true
+	}
+	return nil
+}
+
+func (w *inotifyWatcher) written(name string) {
+	path := w.path.Join(name)
+	stat, err := path.Stat()
+	if err != nil {
+		return
+	}
+	w.sizes[name] = stat.Size()
+	if w.created[name] {
+		delete(w.created, name)
+		w.path.tree.dispatch(CreateFileEvent{newFileEvent(path, stat.Size())})
+	} else {
+		w.path.tree.dispatch(RewriteFileEvent{newFileEvent(path, stat.Size())})
+	}
+}
+
+func (w *inotifyWatcher) remove(name string, isDir bool) {
+	path := w.path.Join(name)
+	if isDir {
+		w.unwatch(name)
+		w.path.tree.dispatch(DeleteDirEvent{newEvent(path)})
+		return
+	}
+	size := w.sizes[name]
+	delete(w.sizes, name)
+	delete(w.created, name)
+	w.path.tree.dispatch(DeleteFileEvent{newFileEvent(path, size)})
+}
+
+func (w *inotifyWatcher) rename(from, to string) {
+	w.rebase(from, to)
+	w.path.tree.dispatch(RenameEvent{newTargetEvent(w.path.Join(from), w.path.Join(to))})
+}
+
+// flushMove treats a pending IN_MOVED_FROM that was not followed by a matching IN_MOVED_TO as a deletion, since the
+// entry has been moved somewhere that isn't being watched.
+func (w *inotifyWatcher) flushMove() {
+	if w.moved != nil {
+		w.remove(w.moved.path, w.moved.isDir)
+		w.moved = nil
+	}
+}
+
+// unwatch removes watches from dir and all its descendants, and forgets about any files within them.
+func (w *inotifyWatcher) unwatch(dir string) {
+	prefix := dir + string(os.PathSeparator)
+	for wd, watched := range w.watches {
+		if watched == dir || strings.HasPrefix(watched, prefix) {
+			_, _ = syscall.InotifyRmWatch(w.fd, uint32(wd))
+			delete(w.watches, wd)
+		}
+	}
+	for name := range w.sizes {
+		if strings.HasPrefix(name, prefix) {
+			delete(w.sizes, name)
+			delete(w.created, name)
+		}
+	}
+}
+
+// rebase updates all the watcher's records of from, and anything within it, to refer to to.
+func (w *inotifyWatcher) rebase(from, to string) {
+	replace := func(name string) (string, bool) {
+		if name == from {
+			return to, true
+		}
+		if strings.HasPrefix(name, from+string(os.PathSeparator)) {
+			return to + name[len(from):], true
+		}
+		return name, false
+	}
+	for wd, watched := range w.watches {
+		w.watches[wd], _ = replace(watched)
+	}
+	for name, size := range w.sizes {
+		if newName, changed := replace(name); changed {
+			delete(w.sizes, name)
+			w.sizes[newName] = size
+		}
+	}
+	for name := range w.created {
+		if newName, changed := replace(name); changed {
+			delete(w.created, name)
+			w.created[newName] = true
+		}
+	}
+}
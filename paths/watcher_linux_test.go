@@ -0,0 +1,123 @@
+//go:build linux
+// +build linux
+
+package paths
+
+import (
+	. "github.com/hx/golib/testing"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestInotifyWatcher(t *testing.T) {
+	tree := NewTree()
+	dir := tree.Join(t.TempDir())
+	dir.Join("change").MustWriteString("old")
+	dir.Join("delete").MustWriteString("delete")
+	dir.Join("move").MustWriteString("move")
+	dir.Join("sub", "a").MustWriteString("a")
+
+	lines := make(chan string, 100)
+	defer tree.Subscribe(func(event Event) { lines <- eventLine(dir, event) })()
+
+	w := dir.Watcher()
+	_, isInotify := w.(*inotifyWatcher)
+	Assert(t, isInotify, "watcher should use inotify")
+	done := make(chan error)
+	go func() { done <- w.Run() }()
+	defer func() {
+		w.Stop()
+		Ok(t, <-done)
+	}()
+
+	// Give the watcher time to establish its watches.
+	time.Sleep(50 * time.Millisecond)
+
+	var events []string
+	// Events are compared without regard to order, since os.RemoveAll doesn't guarantee one.
+	expect := func(expected ...string) {
+		t.Helper()
+		events = events[:0]
+		timeout := time.After(time.Second)
+		for len(events) < len(expected) {
+			select {
+			case line := <-lines:
+				events = append(events, line)
+			case <-timeout:
+				t.Fatalf("timed out with events %v", events)
+			}
+		}
+		sort.Strings(expected)
+		sort.Strings(events)
+		Equals(t, expected, events)
+	}
+	file := func(names ...string) string { return filepath.Join(append([]string{dir.path}, names...)...) }
+
+	Ok(t, os.WriteFile(file("change"), []byte("new"), 0644))
+	Ok(t, os.WriteFile(file("new"), []byte("new"), 0644))
+	Ok(t, os.Remove(file("delete")))
+	Ok(t, os.Rename(file("move"), file("moved")))
+	Ok(t, os.Rename(file("sub"), file("renamed")))
+	expect(
+		"paths.RewriteFileEvent change",
+		"paths.CreateFileEvent new",
+		"paths.DeleteFileEvent delete",
+		"paths.RenameEvent move -> moved",
+		"paths.RenameEvent sub -> renamed",
+	)
+
+	Ok(t, os.Mkdir(file("renamed", "deeper"), 0755))
+	time.Sleep(50 * time.Millisecond)
+	Ok(t, os.WriteFile(file("renamed", "deeper", "b"), []byte("b"), 0644))
+	expect("paths.CreateFileEvent renamed/deeper/b")
+
+	// A move to somewhere that isn't watched has no IN_MOVED_TO, so it's reported as a deletion.
+	Ok(t, os.Rename(file("renamed", "deeper", "b"), filepath.Join(t.TempDir(), "b")))
+	expect("paths.DeleteFileEvent renamed/deeper/b")
+
+	Ok(t, os.RemoveAll(file("renamed")))
+	expect(
+		"paths.DeleteFileEvent renamed/a",
+		"paths.DeleteDirEvent renamed/deeper",
+		"paths.DeleteDirEvent renamed",
+	)
+}
+
+// inotifyEvent encodes an event as the kernel would deliver it, with its name padded with nulls.
+func inotifyEvent(wd int32, mask, cookie uint32, name string) []byte {
+	padded := make([]byte, (len(name)/16+1)*16)
+	copy(padded, name)
+	raw := syscall.InotifyEvent{Wd: wd, Mask: mask, Cookie: cookie, Len: uint32(len(padded))}
+	header := (*[syscall.SizeofInotifyEvent]byte)(unsafe.Pointer(&raw))[:]
+	return append(append([]byte(nil), header...), padded...)
+}
+
+func TestInotifyWatcher_MoveAcrossReads(t *testing.T) {
+	tree := NewTree()
+	dir := tree.Join(t.TempDir())
+	w := &inotifyWatcher{
+		path:    dir,
+		watches: map[int32]string{1: dir.path},
+		sizes:   map[string]int64{dir.Join("from").path: 4, dir.Join("gone").path: 4},
+		created: make(map[string]bool),
+	}
+
+	var events []string
+	defer tree.Subscribe(func(event Event) { events = append(events, eventLine(dir, event)) })()
+
+	Ok(t, w.process(inotifyEvent(1, syscall.IN_MOVED_FROM, 7, "from")))
+	Equals(t, 0, len(events))
+	Ok(t, w.process(inotifyEvent(1, syscall.IN_MOVED_TO, 7, "to")))
+	Equals(t, []string{"paths.RenameEvent from -> to"}, events)
+
+	events = nil
+	Ok(t, w.process(inotifyEvent(1, syscall.IN_MOVED_FROM, 8, "gone")))
+	Equals(t, 0, len(events))
+	w.flushMove()
+	Equals(t, []string{"paths.DeleteFileEvent gone"}, events)
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+// +build !linux
+
+package paths
+
+// Watcher returns a Watcher for p. Native file system notifications are only supported on Linux, so on this platform it
+// is a PollingWatcher using DefaultPollInterval.
+func (p *Path) Watcher() Watcher { return p.PollingWatcher(DefaultPollInterval) }
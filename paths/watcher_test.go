@@ -0,0 +1,79 @@
+package paths
+
+import (
+	"fmt"
+	. "github.com/hx/golib/testing"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// eventLine describes an event in the form "Type name" or "Type name -> target", with names relative to dir.
+func eventLine(dir *Path, event Event) string {
+	name := func(p *Path) string {
+		return strings.ReplaceAll(dir.nameOf(p), string(os.PathSeparator), "/")
+	}
+	line := fmt.Sprintf("%T %s", event, name(event.Path()))
+	if target, ok := event.(TargetEvent); ok {
+		line += " -> " + name(target.Target())
+	}
+	return line
+}
+
+func TestPollingWatcher(t *testing.T) {
+	sys, tree := testSys()
+	dir := tree.Join("watched")
+	dir.Join("keep").MustWriteString("keep")
+	dir.Join("change").MustWriteString("old")
+	dir.Join("delete").MustWriteString("delete")
+	dir.Join("move").MustWriteString("move")
+	dir.Join("sub", "a").MustWriteString("a")
+	dir.Join("sub", "b").MustWriteString("b")
+	dir.Join("gone", "c").MustWriteString("c")
+
+	w := dir.PollingWatcher(time.Hour).(*pollingWatcher)
+	before := w.scan()
+
+	// Make changes directly through the system, so the tree doesn't see them.
+	write := func(name, contents string) {
+		file, err := sys.OpenFile(dir.Join(name).path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+		Ok(t, err)
+		_, err = file.Write([]byte(contents))
+		Ok(t, err)
+		Ok(t, file.Close())
+	}
+	write("change", "new contents")
+	write("new", "new")
+	Ok(t, sys.Remove(dir.Join("delete").path))
+	Ok(t, sys.Remove(dir.Join("gone").path))
+	Ok(t, sys.Rename(dir.Join("move").path, dir.Join("moved").path))
+	Ok(t, sys.Rename(dir.Join("sub").path, dir.Join("renamed").path))
+
+	var events []string
+	defer tree.Subscribe(func(event Event) { events = append(events, eventLine(dir, event)) })()
+	w.compare(before, w.scan())
+
+	Equals(t, []string{
+		"paths.RenameEvent move -> moved",
+		"paths.RenameEvent sub -> renamed",
+		"paths.DeleteFileEvent delete",
+		"paths.DeleteDirEvent gone",
+		"paths.CreateFileEvent new",
+		"paths.RewriteFileEvent change",
+	}, events)
+}
+
+func TestPollingWatcher_Run(t *testing.T) {
+	_, tree := testSys()
+	w := tree.PollingWatcher(time.Millisecond)
+	done := make(chan error)
+	go func() { done <- w.Run() }()
+	w.Stop()
+	select {
+	case err := <-done:
+		Ok(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("watcher did not stop")
+	}
+}